@@ -0,0 +1,59 @@
+package sloggcp
+
+import "log/slog"
+
+// Severity returns a top-level attribute which overrides the [SeverityKey] field
+// of the record with the severity of level, as mapped by the handler,
+// for example by [WithSeverityMapper] or [WithNumericSeverity].
+// Unlike a plain attribute with the same key, it is not subject to the [ReservedKeyPolicy].
+// The level of the record itself is unchanged, so it still determines
+// whether the record is enabled and reported to Error Reporting.
+//
+// Inside a group, or with other handlers, the value resolves to the severity name of [SeverityFromLevel].
+func Severity(level slog.Level) slog.Attr {
+	return slog.Any(SeverityKey, severityOverride(level))
+}
+
+// severityOverride marks a level set by [Severity].
+type severityOverride slog.Level
+
+// LogValue implements [slog.LogValuer].
+func (s severityOverride) LogValue() slog.Value {
+	return slog.StringValue(SeverityFromLevel(slog.Level(s)))
+}
+
+// Message returns a top-level attribute which overrides the [MessageKey] field of the record with msg.
+// Unlike a plain attribute with the same key, it is not subject to the [ReservedKeyPolicy].
+// The message of an error report still takes precedence.
+//
+// Inside a group, or with other handlers, the value resolves to msg.
+func Message(msg string) slog.Attr {
+	return slog.Any(MessageKey, messageOverride(msg))
+}
+
+// messageOverride marks a message set by [Message].
+type messageOverride string
+
+// LogValue implements [slog.LogValuer].
+func (m messageOverride) LogValue() slog.Value {
+	return slog.StringValue(string(m))
+}
+
+// checkOverride converts an attribute of [Severity] or [Message] with its own key into the field value.
+// It returns false for any other attribute.
+func (c *config) checkOverride(a slog.Attr) (slog.Attr, bool) {
+	if a.Value.Kind() != slog.KindLogValuer {
+		return a, false
+	}
+	switch v := a.Value.Any().(type) {
+	case severityOverride:
+		if a.Key == SeverityKey {
+			return slog.Any(SeverityKey, c.severityValue(slog.Level(v))), true
+		}
+	case messageOverride:
+		if a.Key == MessageKey {
+			return slog.String(MessageKey, string(v)), true
+		}
+	}
+	return a, false
+}
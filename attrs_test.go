@@ -0,0 +1,133 @@
+package sloggcp
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestAttrConstructors(t *testing.T) {
+	tests := []struct {
+		name    string
+		options []Option
+		attr    slog.Attr
+		key     string
+		want    any
+	}{
+		{
+			name: "Severity",
+			attr: Severity(slog.LevelWarn),
+			key:  SeverityKey,
+			want: WarningSeverity,
+		},
+		{
+			name:    "Severity numeric",
+			options: []Option{WithNumericSeverity(true)},
+			attr:    Severity(LevelCritical),
+			key:     SeverityKey,
+			want:    float64(600),
+		},
+		{
+			name:    "Severity with ReservedKeyDrop",
+			options: []Option{WithReservedKeyPolicy(ReservedKeyDrop)},
+			attr:    Severity(slog.LevelError),
+			key:     SeverityKey,
+			want:    ErrorSeverity,
+		},
+		{
+			name: "Message",
+			attr: Message("overridden"),
+			key:  MessageKey,
+			want: "overridden",
+		},
+		{
+			name: "Trace",
+			attr: Trace("my-project", "4BF92F3577B34DA6A3CE929D0E0E4736"),
+			key:  TraceKey,
+			want: "projects/my-project/traces/4bf92f3577b34da6a3ce929d0e0e4736",
+		},
+		{
+			name: "SpanID",
+			attr: SpanID("f067aa0ba902b7"),
+			key:  SpanIDKey,
+			want: "00f067aa0ba902b7",
+		},
+		{
+			name: "Labels",
+			attr: LabelsAny("env", "prod", "shard", 3),
+			key:  LabelsKey,
+			want: map[string]any{"env": "prod", "shard": "3"},
+		},
+		{
+			name: "Operation",
+			attr: Operation("op-1", "github.com/muhlemmer/sloggcp", true, false),
+			key:  OperationKey,
+			want: map[string]any{"id": "op-1", "producer": "github.com/muhlemmer/sloggcp", "first": true},
+		},
+		{
+			name: "InsertID",
+			attr: InsertID("entry-1"),
+			key:  InsertIDKey,
+			want: "entry-1",
+		},
+		{
+			name: "HTTP",
+			attr: HTTP(HTTPRequest{RequestMethod: "GET", Status: 200, Latency: 1500 * time.Millisecond}),
+			key:  HTTPRequestKey,
+			want: map[string]any{"requestMethod": "GET", "status": float64(200), "latency": "1.500s"},
+		},
+		{
+			name: "Err",
+			attr: Err(errors.New("boom")),
+			key:  ErrorKey,
+			want: "boom",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			slog.New(NewHandler(&buf, tt.options...)).Info("test message", tt.attr)
+			var got map[string]any
+			if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+				t.Fatalf("Failed to decode log output: %v", err)
+			}
+			if !reflect.DeepEqual(got[tt.key], tt.want) {
+				t.Errorf("%s = %#v, want %#v", tt.key, got[tt.key], tt.want)
+			}
+		})
+	}
+}
+
+func TestAttrConstructors_compose(t *testing.T) {
+	var buf bytes.Buffer
+	slog.New(NewHandler(&buf)).WithGroup("request").Info("test message",
+		Severity(slog.LevelWarn),
+		Trace("my-project", "4bf92f3577b34da6a3ce929d0e0e4736"),
+		SpanID("00f067aa0ba902b7"),
+		Labels("env", "prod"),
+		InsertID("entry-1"),
+		"path", "/",
+	)
+	var got map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+		t.Fatalf("Failed to decode log output: %v", err)
+	}
+	want := map[string]any{
+		SeverityKey: InfoSeverity,
+		MessageKey:  "test message",
+		TraceKey:    "projects/my-project/traces/4bf92f3577b34da6a3ce929d0e0e4736",
+		SpanIDKey:   "00f067aa0ba902b7",
+		LabelsKey:   map[string]any{"env": "prod"},
+		InsertIDKey: "entry-1",
+		// Severity only overrides the field at the top level.
+		"request": map[string]any{SeverityKey: WarningSeverity, "path": "/"},
+	}
+	delete(got, TimeKey)
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("output =\n%v\nwant\n%v", got, want)
+	}
+}
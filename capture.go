@@ -0,0 +1,67 @@
+package sloggcp
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"slices"
+	"sync"
+)
+
+// CaptureHandler is a handler for tests, which keeps the formatted records in memory,
+// instead of writing them.
+// Records are formatted exactly like by [NewHandler], and stored as decoded JSON objects,
+// so that fields can be asserted without decoding the output.
+//
+// Handlers derived by WithAttrs and WithGroup share the records with the CaptureHandler.
+// It is safe for concurrent use.
+type CaptureHandler struct {
+	slog.Handler
+	output *captureOutput
+}
+
+// NewCaptureHandler creates a [CaptureHandler], configured by options.
+func NewCaptureHandler(options ...Option) *CaptureHandler {
+	o := new(captureOutput)
+	return &CaptureHandler{
+		Handler: newHandler(o, newConfig(options)),
+		output:  o,
+	}
+}
+
+// Records returns the captured records, in the order they were handled.
+func (h *CaptureHandler) Records() []map[string]any {
+	h.output.mtx.Lock()
+	defer h.output.mtx.Unlock()
+	return slices.Clone(h.output.records)
+}
+
+// Reset removes all captured records.
+func (h *CaptureHandler) Reset() {
+	h.output.mtx.Lock()
+	defer h.output.mtx.Unlock()
+	h.output.records = nil
+}
+
+type captureOutput struct {
+	mtx     sync.Mutex // protects records
+	records []map[string]any
+}
+
+func (o *captureOutput) write(_ *slog.Record, state *handleState) error {
+	if err := state.encoder.Encode(state.out); err != nil {
+		return fmt.Errorf("sloggcp handler: %w", err)
+	}
+	var record map[string]any
+	if err := json.Unmarshal(state.buf.Bytes(), &record); err != nil {
+		return fmt.Errorf("sloggcp handler: %w", err)
+	}
+	o.mtx.Lock()
+	defer o.mtx.Unlock()
+	o.records = append(o.records, record)
+	return nil
+}
+
+func (o *captureOutput) Flush() error { return nil }
+
+func (o *captureOutput) Close() error { return nil }
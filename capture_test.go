@@ -0,0 +1,49 @@
+package sloggcp
+
+import (
+	"log/slog"
+	"sync"
+	"testing"
+)
+
+func TestCaptureHandler(t *testing.T) {
+	h := NewCaptureHandler(WithProjectID("my-project"))
+	logger := slog.New(h).With("foo", "bar")
+	logger.Info("info message", "count", 1)
+	logger.WithGroup("group").Error("error message", "error", mockStackTraceError{})
+
+	records := h.Records()
+	if len(records) != 2 {
+		t.Fatalf("got %d records, want 2", len(records))
+	}
+	if got := records[0]; got[MessageKey] != "info message" || got[SeverityKey] != InfoSeverity ||
+		got["foo"] != "bar" || got["count"] != float64(1) {
+		t.Errorf("records[0] = %v", got)
+	}
+	if got := records[1]; got[ErrorReportTypeKey] != ErrorReportTypeValue || got[MessageKey] != "stack" ||
+		got["group"].(map[string]any)[ErrorKey] != "mockStackTraceError" {
+		t.Errorf("records[1] = %v", got)
+	}
+
+	h.Reset()
+	if records := h.Records(); len(records) != 0 {
+		t.Errorf("got %d records after Reset, want 0", len(records))
+	}
+}
+
+func TestCaptureHandler_concurrent(t *testing.T) {
+	h := NewCaptureHandler()
+	logger := slog.New(h)
+	var wg sync.WaitGroup
+	for i := range 10 {
+		wg.Go(func() {
+			for range 10 {
+				logger.Info("concurrent message", "goroutine", i)
+			}
+		})
+	}
+	wg.Wait()
+	if records := h.Records(); len(records) != 100 {
+		t.Errorf("got %d records, want 100", len(records))
+	}
+}
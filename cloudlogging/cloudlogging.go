@@ -0,0 +1,93 @@
+// Package cloudlogging provides a [slog.Handler] which writes to the Cloud Logging API,
+// through a *logging.Logger of the cloud.google.com/go/logging package.
+// It is a separate module, so that the sloggcp module does not depend on the client library.
+package cloudlogging
+
+import (
+	"log/slog"
+	"net/http"
+
+	"cloud.google.com/go/logging"
+	"cloud.google.com/go/logging/apiv2/loggingpb"
+	"github.com/muhlemmer/sloggcp"
+)
+
+// NewClientHandler creates a handler which maps each record to a logging.Entry,
+// passed to logger.Log.
+// Severity, error reporting and all other options are handled the same as by [sloggcp.NewHandler].
+// The opts are applied by [sloggcp.WithHandlerOptions], before the other options.
+//
+// Batching is left to the client. The returned handler provides a Flush() error method,
+// which calls logger.Flush.
+func NewClientHandler(logger *logging.Logger, opts *slog.HandlerOptions, options ...sloggcp.Option) slog.Handler {
+	if opts != nil {
+		options = append([]sloggcp.Option{sloggcp.WithHandlerOptions(opts)}, options...)
+	}
+	return sloggcp.NewEntryHandler(clientWriter{logger}, options...)
+}
+
+// clientWriter is the [sloggcp.EntryWriter] of [NewClientHandler].
+// The embedded Flush method is called by the handler's Flush.
+type clientWriter struct {
+	*logging.Logger
+}
+
+func (w clientWriter) WriteEntry(e sloggcp.Entry) error {
+	w.Log(toLoggingEntry(e))
+	return nil
+}
+
+// toLoggingEntry converts e to a logging.Entry.
+func toLoggingEntry(e sloggcp.Entry) logging.Entry {
+	le := logging.Entry{
+		Timestamp:    e.Timestamp,
+		Severity:     logging.ParseSeverity(e.Severity),
+		Labels:       e.Labels,
+		Trace:        e.Trace,
+		SpanID:       e.SpanID,
+		TraceSampled: e.TraceSampled,
+		InsertID:     e.InsertID,
+		HTTPRequest:  httpRequest(e.HTTPRequest),
+		Payload:      e.Payload,
+	}
+	if s := e.SourceLocation; s != nil {
+		le.SourceLocation = &loggingpb.LogEntrySourceLocation{
+			File:     s.File,
+			Line:     int64(s.Line),
+			Function: s.Function,
+		}
+	}
+	return le
+}
+
+// httpRequest converts r to a logging.HTTPRequest,
+// whose request fields are taken from a [http.Request].
+func httpRequest(r *sloggcp.HTTPRequest) *logging.HTTPRequest {
+	if r == nil {
+		return nil
+	}
+	req, err := http.NewRequest(r.RequestMethod, r.RequestURL, nil)
+	if err != nil {
+		req = &http.Request{Method: r.RequestMethod, Header: make(http.Header)}
+	}
+	if r.UserAgent != "" {
+		req.Header.Set("User-Agent", r.UserAgent)
+	}
+	if r.Referer != "" {
+		req.Header.Set("Referer", r.Referer)
+	}
+	req.Proto = r.Protocol
+	return &logging.HTTPRequest{
+		Request:                        req,
+		RequestSize:                    r.RequestSize,
+		Status:                         r.Status,
+		ResponseSize:                   r.ResponseSize,
+		Latency:                        r.Latency,
+		LocalIP:                        r.ServerIP,
+		RemoteIP:                       r.RemoteIP,
+		CacheHit:                       r.CacheHit,
+		CacheValidatedWithOriginServer: r.CacheValidatedWithOriginServer,
+		CacheFillBytes:                 r.CacheFillBytes,
+		CacheLookup:                    r.CacheLookup,
+	}
+}
@@ -0,0 +1,68 @@
+package cloudlogging
+
+import (
+	"encoding/json"
+	"log/slog"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/logging"
+	"github.com/muhlemmer/sloggcp"
+)
+
+func Test_toLoggingEntry(t *testing.T) {
+	now := time.Now()
+	e := sloggcp.Entry{
+		Timestamp:      now,
+		Severity:       sloggcp.ErrorSeverity,
+		Labels:         map[string]string{"tenant": "acme"},
+		Trace:          "projects/my-project/traces/abc",
+		SpanID:         "def",
+		TraceSampled:   true,
+		SourceLocation: &slog.Source{File: "main.go", Line: 42, Function: "main.main"},
+		HTTPRequest: &sloggcp.HTTPRequest{
+			RequestMethod: "GET",
+			RequestURL:    "https://example.com/path",
+			Status:        500,
+			UserAgent:     "test-agent",
+		},
+		InsertID: "id",
+		Payload:  json.RawMessage(`{"message":"test message"}`),
+	}
+	got := toLoggingEntry(e)
+	if !got.Timestamp.Equal(now) {
+		t.Errorf("Timestamp = %v, want %v", got.Timestamp, now)
+	}
+	if got.Severity != logging.Error {
+		t.Errorf("Severity = %v, want %v", got.Severity, logging.Error)
+	}
+	if got.Labels["tenant"] != "acme" {
+		t.Errorf("Labels = %v, want tenant label", got.Labels)
+	}
+	if got.Trace != e.Trace || got.SpanID != e.SpanID || !got.TraceSampled {
+		t.Errorf("trace = %q %q %v, want %q %q true", got.Trace, got.SpanID, got.TraceSampled, e.Trace, e.SpanID)
+	}
+	if got.InsertID != "id" {
+		t.Errorf("InsertID = %q, want %q", got.InsertID, "id")
+	}
+	if sl := got.SourceLocation; sl == nil || sl.File != "main.go" || sl.Line != 42 || sl.Function != "main.main" {
+		t.Errorf("SourceLocation = %v, want main.go:42 main.main", sl)
+	}
+	if hr := got.HTTPRequest; hr == nil || hr.Status != 500 || hr.Request.Method != "GET" ||
+		hr.Request.URL.String() != "https://example.com/path" || hr.Request.UserAgent() != "test-agent" {
+		t.Errorf("HTTPRequest = %v, want GET https://example.com/path", hr)
+	}
+	if payload, ok := got.Payload.(json.RawMessage); !ok || string(payload) != string(e.Payload) {
+		t.Errorf("Payload = %v, want %s", got.Payload, e.Payload)
+	}
+}
+
+func Test_toLoggingEntry_empty(t *testing.T) {
+	got := toLoggingEntry(sloggcp.Entry{Severity: sloggcp.InfoSeverity})
+	if got.Severity != logging.Info {
+		t.Errorf("Severity = %v, want %v", got.Severity, logging.Info)
+	}
+	if got.SourceLocation != nil || got.HTTPRequest != nil {
+		t.Errorf("SourceLocation, HTTPRequest = %v, %v, want nil", got.SourceLocation, got.HTTPRequest)
+	}
+}
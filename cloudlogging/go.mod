@@ -0,0 +1,8 @@
+module github.com/muhlemmer/sloggcp/cloudlogging
+
+go 1.25.0
+
+require (
+	cloud.google.com/go/logging v1.13.0
+	github.com/muhlemmer/sloggcp v0.0.0-20261014180135-85239c16f0a0
+)
@@ -0,0 +1,64 @@
+package sloggcp
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"runtime"
+	"slices"
+	"time"
+)
+
+type contextAttrsKey struct{}
+
+// ContextWithAttrs returns a copy of ctx carrying attrs,
+// which the handler adds to every record logged with the returned context.
+// Attributes already carried by ctx are kept and attrs are appended to them.
+//
+// The attributes are added at the top level, before the attributes of the logger and the record,
+// so that the latter take precedence for duplicate keys.
+// Use [WithContextAttrsGroup] to add them to a group instead.
+func ContextWithAttrs(ctx context.Context, attrs ...slog.Attr) context.Context {
+	if len(attrs) == 0 {
+		return ctx
+	}
+	prev := contextAttrs(ctx)
+	return context.WithValue(ctx, contextAttrsKey{}, append(slices.Clip(prev), attrs...))
+}
+
+// contextAttrs returns the attributes carried by ctx.
+func contextAttrs(ctx context.Context) []slog.Attr {
+	if ctx == nil {
+		return nil
+	}
+	attrs, _ := ctx.Value(contextAttrsKey{}).([]slog.Attr)
+	return attrs
+}
+
+// ContextErrorKey is the attribute key used by [LogContextError] for the context error.
+// It is not an error key, so the record is not reported to Error Reporting.
+const ContextErrorKey = "contextError"
+
+// LogContextError logs msg when ctx is done, with the cause of ctx under [ContextErrorKey].
+// Cancellation is logged at [LevelNotice] and an exceeded deadline at [LevelWarning],
+// so that expected client disconnects and timeouts do not pollute Error Reporting.
+// Nothing is logged if ctx is not done.
+func LogContextError(ctx context.Context, logger *slog.Logger, msg string) {
+	var level slog.Level
+	switch err := ctx.Err(); {
+	case err == nil:
+		return
+	case errors.Is(err, context.DeadlineExceeded):
+		level = LevelWarning
+	default:
+		level = LevelNotice
+	}
+	if !logger.Enabled(ctx, level) {
+		return
+	}
+	var pcs [1]uintptr
+	runtime.Callers(2, pcs[:]) // skip [runtime.Callers, LogContextError]
+	r := slog.NewRecord(time.Now(), level, msg, pcs[0])
+	r.AddAttrs(slog.String(ContextErrorKey, context.Cause(ctx).Error()))
+	_ = logger.Handler().Handle(ctx, r)
+}
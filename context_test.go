@@ -0,0 +1,190 @@
+package sloggcp
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestContextWithAttrs(t *testing.T) {
+	ctx := ContextWithAttrs(context.Background(), slog.String("request_id", "req-1"))
+	ctx = ContextWithAttrs(ctx, slog.String("user", "alice"), Labels("tenant", "acme"))
+	// a sibling context must not affect ctx
+	_ = ContextWithAttrs(ctx, slog.String("sibling", "value"))
+
+	tests := []struct {
+		name    string
+		options []Option
+		log     func(*slog.Logger)
+		want    map[string]any
+	}{
+		{
+			name: "top-level",
+			log: func(l *slog.Logger) {
+				l.WithGroup("group").InfoContext(ctx, "test message", "foo", "bar")
+			},
+			want: map[string]any{
+				"request_id": "req-1",
+				"user":       "alice",
+				LabelsKey:    map[string]any{"tenant": "acme"},
+				"group":      map[string]any{"foo": "bar"},
+			},
+		},
+		{
+			name: "record attrs take precedence",
+			log: func(l *slog.Logger) {
+				l.InfoContext(ctx, "test message", "user", "bob")
+			},
+			want: map[string]any{
+				"request_id": "req-1",
+				"user":       "bob",
+				LabelsKey:    map[string]any{"tenant": "acme"},
+			},
+		},
+		{
+			name:    "context group",
+			options: []Option{WithContextAttrsGroup("ctx")},
+			log: func(l *slog.Logger) {
+				l.InfoContext(ctx, "test message")
+			},
+			want: map[string]any{
+				"ctx": map[string]any{
+					"request_id": "req-1",
+					"user":       "alice",
+				},
+				LabelsKey: map[string]any{"tenant": "acme"},
+			},
+		},
+		{
+			name:    "context group and WithGroup of the same name",
+			options: []Option{WithContextAttrsGroup("ctx")},
+			log: func(l *slog.Logger) {
+				l.WithGroup("ctx").InfoContext(ctx, "test message", "a", 1)
+			},
+			want: map[string]any{
+				"ctx": map[string]any{
+					"request_id": "req-1",
+					"user":       "alice",
+					"a":          float64(1),
+				},
+				LabelsKey: map[string]any{"tenant": "acme"},
+			},
+		},
+		{
+			name: "error from context",
+			log: func(l *slog.Logger) {
+				l.ErrorContext(ContextWithAttrs(context.Background(), slog.String("error", "oops")), "test message")
+			},
+			want: map[string]any{
+				ErrorReportTypeKey: ErrorReportTypeValue,
+				MessageKey:         "oops",
+				ErrorKey:           "oops",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			tt.log(slog.New(NewHandler(&buf, tt.options...)))
+
+			var got map[string]any
+			if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+				t.Fatalf("Failed to decode log output: %v", err)
+			}
+			delete(got, TimeKey)
+			delete(got, SeverityKey)
+			delete(got, EventTimeKey)
+			if _, ok := tt.want[MessageKey]; !ok {
+				delete(got, MessageKey)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("log output = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLogContextError(t *testing.T) {
+	canceled, cancel := context.WithCancel(context.Background())
+	cancel()
+	expired, cancelExpired := context.WithDeadline(context.Background(), time.Now().Add(-time.Second))
+	defer cancelExpired()
+
+	tests := []struct {
+		name string
+		ctx  context.Context
+		want map[string]any
+	}{
+		{
+			name: "canceled",
+			ctx:  canceled,
+			want: map[string]any{
+				SeverityKey:     NoticeSeverity,
+				MessageKey:      "request aborted",
+				ContextErrorKey: context.Canceled.Error(),
+			},
+		},
+		{
+			name: "deadline exceeded",
+			ctx:  expired,
+			want: map[string]any{
+				SeverityKey:     WarningSeverity,
+				MessageKey:      "request aborted",
+				ContextErrorKey: context.DeadlineExceeded.Error(),
+			},
+		},
+		{
+			name: "nil error",
+			ctx:  context.Background(),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			LogContextError(tt.ctx, slog.New(NewHandler(&buf)), "request aborted")
+			if tt.want == nil {
+				if buf.Len() != 0 {
+					t.Errorf("unexpected log output: %s", buf.String())
+				}
+				return
+			}
+
+			var got map[string]any
+			if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+				t.Fatalf("Failed to decode log output: %v", err)
+			}
+			delete(got, TimeKey)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("log output = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLogContextError_source(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	var buf bytes.Buffer
+	LogContextError(ctx, slog.New(NewHandler(&buf, WithHandlerOptions(&slog.HandlerOptions{AddSource: true}))), "request aborted")
+	var got struct {
+		Source slog.Source `json:"logging.googleapis.com/sourceLocation"`
+	}
+	if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+		t.Fatalf("Failed to decode log output: %v", err)
+	}
+	if file := filepath.Base(got.Source.File); file != "context_test.go" {
+		t.Errorf("source file = %q, want %q", file, "context_test.go")
+	}
+
+	buf.Reset()
+	LogContextError(ctx, slog.New(NewHandler(&buf, WithMinLevel(LevelError))), "request aborted")
+	if buf.Len() != 0 {
+		t.Errorf("record below the minimum level logged: %s", buf.String())
+	}
+}
@@ -0,0 +1,150 @@
+package sloggcp
+
+import (
+	"container/list"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// DedupSummaryKey is the field which reports the number of errors suppressed by [WithErrorDedup].
+const DedupSummaryKey = "dedupSummary"
+
+// maxDedupKeys bounds the number of error keys remembered by [WithErrorDedup].
+const maxDedupKeys = 1024
+
+// WithErrorDedup suppresses repeated error reports within window.
+// Errors are identical when they have the same error string and are logged from the same call site.
+// The first error starts the window and is reported, identical errors within the window are dropped.
+// The first identical error after the window is reported with a [DedupSummaryKey] field,
+// such as "suppressed 99 identical errors", and starts a new window.
+//
+// Flush and Close of the handler report the errors suppressed so far as well,
+// by the last suppressed error with the [DedupSummaryKey] field,
+// so that the suppressed errors of a logger which becomes quiet are reported on shutdown.
+// Otherwise, summaries are only reported with the next identical error after the window.
+//
+// Records which are no error reports are not affected.
+// The most recent 1024 distinct errors are remembered.
+func WithErrorDedup(window time.Duration) Option {
+	return func(c *config) {
+		c.dedup = &deduplicator{
+			window:  window,
+			entries: make(map[string]*list.Element),
+			lru:     list.New(),
+		}
+	}
+}
+
+// deduplicator keeps track of recent errors in a LRU list.
+type deduplicator struct {
+	window time.Duration
+
+	mtx     sync.Mutex // protects entries and lru
+	entries map[string]*list.Element
+	lru     *list.List // of *dedupEntry, most recently used first
+}
+
+type dedupEntry struct {
+	key        string
+	start      time.Time
+	suppressed int
+	last       dedupRecord // the last suppressed record
+}
+
+// dedupRecord is a suppressed record and the handler which suppressed it,
+// for reporting the summary by [handler.Flush].
+type dedupRecord struct {
+	h          *handler
+	ctx        context.Context
+	r          slog.Record
+	suppressed int
+}
+
+// dedupSummaryContextKey carries the number of suppressed errors
+// of a summary record reported by [handler.Flush].
+type dedupSummaryContextKey struct{}
+
+// allow reports whether the error identified by key is reported at now.
+// When the error is reported, suppressed is the number of identical errors
+// suppressed in the previous window.
+// When the error is suppressed, rec is kept for [deduplicator.pending].
+func (d *deduplicator) allow(key string, now time.Time, rec dedupRecord) (ok bool, suppressed int) {
+	d.mtx.Lock()
+	defer d.mtx.Unlock()
+	if elem, found := d.entries[key]; found {
+		d.lru.MoveToFront(elem)
+		e := elem.Value.(*dedupEntry)
+		if now.Sub(e.start) < d.window {
+			e.suppressed++
+			rec.r = rec.r.Clone()
+			e.last = rec
+			return false, 0
+		}
+		suppressed = e.suppressed
+		e.start, e.suppressed, e.last = now, 0, dedupRecord{}
+		return true, suppressed
+	}
+	if d.lru.Len() >= maxDedupKeys {
+		oldest := d.lru.Back()
+		d.lru.Remove(oldest)
+		delete(d.entries, oldest.Value.(*dedupEntry).key)
+	}
+	d.entries[key] = d.lru.PushFront(&dedupEntry{key: key, start: now})
+	return true, 0
+}
+
+// pending returns the last suppressed record of each error with suppressed errors,
+// with the number of suppressed errors, which is reset.
+func (d *deduplicator) pending() []dedupRecord {
+	d.mtx.Lock()
+	defer d.mtx.Unlock()
+	var records []dedupRecord
+	for elem := d.lru.Front(); elem != nil; elem = elem.Next() {
+		e := elem.Value.(*dedupEntry)
+		if e.suppressed == 0 || e.last.h == nil {
+			continue
+		}
+		rec := e.last
+		rec.suppressed = e.suppressed
+		records = append(records, rec)
+		e.suppressed, e.last = 0, dedupRecord{}
+	}
+	return records
+}
+
+// reportPending reports the summaries of the errors suppressed so far by [WithErrorDedup].
+func (c *config) reportPending() error {
+	if c.dedup == nil {
+		return nil
+	}
+	var errs []error
+	for _, rec := range c.dedup.pending() {
+		ctx := context.WithValue(rec.ctx, dedupSummaryContextKey{}, rec.suppressed)
+		errs = append(errs, rec.h.Handle(ctx, rec.r))
+	}
+	return errors.Join(errs...)
+}
+
+// dedupKey identifies an error value logged at the call site pc.
+func dedupKey(value any, pc uintptr) string {
+	var s string
+	switch v := value.(type) {
+	case error:
+		s = v.Error()
+	case string:
+		s = v
+	default:
+		s = fmt.Sprint(v)
+	}
+	return strconv.FormatUint(uint64(pc), 16) + ":" + s
+}
+
+// dedupSummary formats the [DedupSummaryKey] field.
+func dedupSummary(suppressed int) string {
+	return fmt.Sprintf("suppressed %d identical errors", suppressed)
+}
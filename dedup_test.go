@@ -0,0 +1,153 @@
+package sloggcp
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestWithErrorDedup(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(NewHandler(&buf, WithErrorDedup(time.Hour)))
+	for range 100 {
+		logger.Error("test message", "error", errors.New("oops"))
+	}
+	for range 3 {
+		logger.Error("test message", "error", errors.New("other"))
+	}
+	for range 5 {
+		logger.Info("info message")
+	}
+
+	counts := make(map[string]int)
+	for line := range bytes.Lines(buf.Bytes()) {
+		var got expectSchema
+		if err := json.Unmarshal(line, &got); err != nil {
+			t.Fatalf("Failed to decode log output: %v", err)
+		}
+		counts[got.Severity]++
+	}
+	if counts[ErrorSeverity] != 2 {
+		t.Errorf("error records = %d, want 2", counts[ErrorSeverity])
+	}
+	if counts[InfoSeverity] != 5 {
+		t.Errorf("info records = %d, want 5", counts[InfoSeverity])
+	}
+}
+
+func TestWithErrorDedup_summary(t *testing.T) {
+	var buf bytes.Buffer
+	h := NewHandler(&buf, WithErrorDedup(time.Minute))
+	start := time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)
+	for i := range 11 {
+		r := slog.NewRecord(start.Add(time.Duration(i)*time.Second), slog.LevelError, "test message", 1)
+		r.AddAttrs(slog.Any("error", errors.New("oops")))
+		if err := h.Handle(t.Context(), r); err != nil {
+			t.Fatal(err)
+		}
+	}
+	r := slog.NewRecord(start.Add(time.Minute), slog.LevelError, "test message", 1)
+	r.AddAttrs(slog.Any("error", errors.New("oops")))
+	if err := h.Handle(t.Context(), r); err != nil {
+		t.Fatal(err)
+	}
+
+	var lines []map[string]any
+	for line := range bytes.Lines(buf.Bytes()) {
+		var got map[string]any
+		if err := json.Unmarshal(line, &got); err != nil {
+			t.Fatalf("Failed to decode log output: %v", err)
+		}
+		lines = append(lines, got)
+	}
+	if len(lines) != 2 {
+		t.Fatalf("records = %d, want 2\n%s", len(lines), buf.String())
+	}
+	if _, ok := lines[0][DedupSummaryKey]; ok {
+		t.Errorf("unexpected %s in first record: %v", DedupSummaryKey, lines[0])
+	}
+	if got, want := lines[1][DedupSummaryKey], "suppressed 10 identical errors"; got != want {
+		t.Errorf("%s = %v, want %v", DedupSummaryKey, got, want)
+	}
+}
+
+func TestWithErrorDedup_flush(t *testing.T) {
+	var buf bytes.Buffer
+	h := NewHandler(&buf, WithErrorDedup(time.Minute))
+	logger := slog.New(h).With("request", "r-1")
+	start := time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)
+	handle := func(at time.Duration) {
+		t.Helper()
+		r := slog.NewRecord(start.Add(at), slog.LevelError, "test message", 1)
+		r.AddAttrs(slog.Any("error", errors.New("oops")))
+		if err := logger.Handler().Handle(t.Context(), r); err != nil {
+			t.Fatal(err)
+		}
+	}
+	flush := func() {
+		t.Helper()
+		if err := h.(interface{ Flush() error }).Flush(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for i := range 11 {
+		handle(time.Duration(i) * time.Second)
+	}
+	flush()
+	flush() // nothing suppressed since the last flush
+	handle(20 * time.Second)
+	handle(time.Minute)
+
+	var lines []map[string]any
+	for line := range bytes.Lines(buf.Bytes()) {
+		var got map[string]any
+		if err := json.Unmarshal(line, &got); err != nil {
+			t.Fatalf("Failed to decode log output: %v", err)
+		}
+		lines = append(lines, got)
+	}
+	if len(lines) != 3 {
+		t.Fatalf("records = %d, want 3\n%s", len(lines), buf.String())
+	}
+	if got, want := lines[1][DedupSummaryKey], "suppressed 10 identical errors"; got != want {
+		t.Errorf("flushed %s = %v, want %v", DedupSummaryKey, got, want)
+	}
+	if got, want := lines[1][TimeKey], "2025-01-02T03:04:15Z"; got != want {
+		t.Errorf("flushed %s = %v, want the last suppressed record %v", TimeKey, got, want)
+	}
+	if got := lines[1]["request"]; got != "r-1" {
+		t.Errorf("flushed request = %v, want the attributes of the logger", got)
+	}
+	if got, want := lines[2][DedupSummaryKey], "suppressed 1 identical errors"; got != want {
+		t.Errorf("%s after the window = %v, want %v", DedupSummaryKey, got, want)
+	}
+}
+
+func Test_deduplicator_lru(t *testing.T) {
+	d := newTestDeduplicator(time.Hour)
+	now := time.Now()
+	for i := range maxDedupKeys + 1 {
+		if ok, _ := d.allow(strconv.Itoa(i), now, dedupRecord{}); !ok {
+			t.Fatalf("key %d suppressed", i)
+		}
+	}
+	if got := d.lru.Len(); got != maxDedupKeys {
+		t.Errorf("lru length = %d, want %d", got, maxDedupKeys)
+	}
+	// key 0 was evicted, so it is reported again
+	if ok, _ := d.allow("0", now, dedupRecord{}); !ok {
+		t.Error("evicted key suppressed")
+	}
+	if ok, _ := d.allow(strconv.Itoa(maxDedupKeys), now, dedupRecord{}); ok {
+		t.Error("recent key not suppressed")
+	}
+}
+
+// newTestDeduplicator returns the deduplicator configured by [WithErrorDedup].
+func newTestDeduplicator(window time.Duration) *deduplicator {
+	return newConfig([]Option{WithErrorDedup(window)}).dedup
+}
@@ -0,0 +1,46 @@
+package sloggcp
+
+import (
+	"io"
+	"log/slog"
+	"os"
+	"slices"
+)
+
+// defaultLoggerOptions are applied by [Default] before the caller's options.
+var defaultLoggerOptions = []Option{
+	WithHandlerOptions(&slog.HandlerOptions{
+		AddSource: true,
+		Level:     slog.LevelInfo,
+	}),
+}
+
+// Default returns a ready to use logger, writing GCP compatible JSON logs
+// to [os.Stdout], at [LevelInfo] and with source locations enabled.
+// The writer can be changed with [WithWriter], the level with [WithMinLevel]
+// and all other settings with the respective options.
+func Default(options ...Option) *slog.Logger {
+	c := newConfig(append(slices.Clip(defaultLoggerOptions), options...))
+	w := c.writer
+	if w == nil {
+		w = os.Stdout
+	}
+	return slog.New(newWriterHandler(w, c))
+}
+
+// SetDefault creates a logger like [Default], and makes it the default logger
+// by calling [slog.SetDefault]. The logger is also returned.
+func SetDefault(options ...Option) *slog.Logger {
+	logger := Default(options...)
+	slog.SetDefault(logger)
+	return logger
+}
+
+// WithWriter sets the writer of the logger created by [Default] and [SetDefault].
+// The option only applies to them: it is ignored by [NewHandler], [NewErrorReportingHandler]
+// and [NewEntryHandler], which write to the writer passed to them.
+func WithWriter(w io.Writer) Option {
+	return func(c *config) {
+		c.writer = w
+	}
+}
@@ -0,0 +1,64 @@
+package sloggcp
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"testing"
+)
+
+func TestDefault(t *testing.T) {
+	var buf bytes.Buffer
+	logger := Default(WithWriter(&buf))
+	logger.Debug("debug message")
+	if buf.Len() != 0 {
+		t.Fatalf("debug record logged at default level: %s", buf.String())
+	}
+	logger.Info("info message")
+
+	var got expectSchema
+	if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+		t.Fatalf("Failed to decode log output: %v", err)
+	}
+	if got.Severity != InfoSeverity {
+		t.Errorf("severity = %q, want %q", got.Severity, InfoSeverity)
+	}
+	if got.Source.Function != "github.com/muhlemmer/sloggcp.TestDefault" {
+		t.Errorf("source function = %q, want test function", got.Source.Function)
+	}
+
+	buf.Reset()
+	Default(WithWriter(&buf), WithMinLevel(slog.LevelDebug)).Debug("debug message")
+	if buf.Len() == 0 {
+		t.Error("debug record not logged with WithMinLevel")
+	}
+
+	var calls int
+	Default(WithWriter(&buf), func(*config) { calls++ })
+	if calls != 1 {
+		t.Errorf("option applied %d times, want 1", calls)
+	}
+}
+
+func TestWithWriter(t *testing.T) {
+	var buf, ignored bytes.Buffer
+	slog.New(NewHandler(&buf, WithWriter(&ignored))).Info("info message")
+	if buf.Len() == 0 || ignored.Len() != 0 {
+		t.Errorf("NewHandler wrote %q to its writer and %q to the WithWriter writer, want the writer only", buf.String(), ignored.String())
+	}
+}
+
+func TestSetDefault(t *testing.T) {
+	prev := slog.Default()
+	defer slog.SetDefault(prev)
+
+	var buf bytes.Buffer
+	logger := SetDefault(WithWriter(&buf))
+	if slog.Default() != logger {
+		t.Fatal("SetDefault() did not set the default logger")
+	}
+	slog.Info("info message")
+	if buf.Len() == 0 {
+		t.Error("default logger did not write to the configured writer")
+	}
+}
@@ -0,0 +1,194 @@
+package sloggcp
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// NewDevHandler creates a handler for local development,
+// which writes colorized, human readable lines instead of JSON:
+//
+//	15:04:05.000 ERROR message key=value group.key=value
+//	    error message or stack trace
+//
+// Records are processed the same as by [NewHandler], including severity mapping and error reporting,
+// so that the output shows the same information in a different format.
+// The output is not meant to be consumed by GCP.
+func NewDevHandler(w io.Writer, options ...Option) slog.Handler {
+	return NewHandler(w, append(slices.Clip(options), WithDevMode(true))...)
+}
+
+// WithDevMode makes [NewHandler] create the handler of [NewDevHandler].
+// This allows switching the output format from the environment,
+// without changing the code which creates the handler:
+//
+//	h := sloggcp.NewHandler(os.Stdout, sloggcp.WithDevMode(os.Getenv("K_SERVICE") == ""))
+func WithDevMode(enable bool) Option {
+	return func(c *config) {
+		c.devMode = enable
+	}
+}
+
+// devOutput writes each record as a colorized, human readable line.
+type devOutput struct {
+	// writes the lines to the writer and the tees of [WithTees]
+	writer  *writerOutput
+	timeKey string // the time field, which is rendered in the line header
+	utc     bool   // the header time is in UTC, set by [WithUTC]
+}
+
+// ANSI escape sequences for the severity colors.
+const (
+	ansiReset   = "\x1b[0m"
+	ansiGray    = "\x1b[90m"
+	ansiBlue    = "\x1b[34m"
+	ansiCyan    = "\x1b[36m"
+	ansiYellow  = "\x1b[33m"
+	ansiRed     = "\x1b[31m"
+	ansiBoldRed = "\x1b[1;31m"
+)
+
+func severityColor(severity string) string {
+	switch severity {
+	case DebugSeverity:
+		return ansiGray
+	case InfoSeverity:
+		return ansiBlue
+	case NoticeSeverity:
+		return ansiCyan
+	case WarningSeverity:
+		return ansiYellow
+	case ErrorSeverity:
+		return ansiRed
+	case CriticalSeverity, AlertSeverity, EmergencySeverity:
+		return ansiBoldRed
+	default:
+		return ""
+	}
+}
+
+// devSkipKeys are rendered in the line header or not at all.
+var devSkipKeys = map[string]bool{
+	SeverityKey:        true,
+	MessageKey:         true,
+	TimeKey:            true,
+	TimestampKey:       true,
+	ErrorReportTypeKey: true,
+	EventTimeKey:       true,
+}
+
+func (o *devOutput) write(r *slog.Record, state *handleState) error {
+	buf := &state.buf
+	if !r.Time.IsZero() {
+		t := r.Time
+		if o.utc {
+			t = t.UTC()
+		}
+		buf.WriteString(t.Format("15:04:05.000"))
+		buf.WriteByte(' ')
+	}
+	severity := severityName(state.out[SeverityKey])
+	if color := severityColor(severity); color != "" {
+		buf.WriteString(color + severity + ansiReset)
+	} else {
+		buf.WriteString(severity)
+	}
+	if r.Message != "" {
+		buf.WriteByte(' ')
+		buf.WriteString(r.Message)
+	}
+	delete(state.out, o.timeKey)
+	writeDevAttrs(buf, "", state.out)
+	buf.WriteByte('\n')
+	if _, isReport := state.out[ErrorReportTypeKey]; isReport {
+		if msg, _ := state.out[MessageKey].(string); msg != "" && msg != r.Message {
+			for line := range strings.Lines(msg) {
+				buf.WriteString("    ")
+				buf.WriteString(strings.TrimSuffix(line, "\n"))
+				buf.WriteByte('\n')
+			}
+		}
+	}
+	return o.writer.writeData(r, buf.Bytes())
+}
+
+// writeDevAttrs writes the members of m as sorted key=value pairs.
+// Nested objects are flattened with dot separated keys.
+func writeDevAttrs(buf *bytes.Buffer, prefix string, m map[string]any) {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		if prefix != "" || !devSkipKeys[k] {
+			keys = append(keys, k)
+		}
+	}
+	slices.Sort(keys)
+	for _, k := range keys {
+		switch v := m[k].(type) {
+		case map[string]any:
+			writeDevAttrs(buf, prefix+k+".", v)
+		case map[string]string:
+			nested := make(map[string]any, len(v))
+			for nk, nv := range v {
+				nested[nk] = nv
+			}
+			writeDevAttrs(buf, prefix+k+".", nested)
+		default:
+			buf.WriteString(" " + prefix + k + "=" + devValue(v))
+		}
+	}
+}
+
+// devValue formats v for the dev output.
+func devValue(v any) string {
+	switch v := v.(type) {
+	case string:
+		return quoteIfNeeded(v)
+	case *gcpSource:
+		return v.File + ":" + strconv.Itoa(v.Line)
+	case time.Time:
+		return v.Format(time.RFC3339Nano)
+	case nil:
+		return "<nil>"
+	}
+	switch reflect.ValueOf(v).Kind() {
+	case reflect.Bool, reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return fmt.Sprint(v)
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return quoteIfNeeded(fmt.Sprint(v))
+	}
+	return string(data)
+}
+
+// quoteIfNeeded quotes s if it is empty or contains spaces, quotes or non-printable characters.
+func quoteIfNeeded(s string) string {
+	if s == "" {
+		return `""`
+	}
+	for _, r := range s {
+		if unicode.IsSpace(r) || r == '"' || r == '=' || !unicode.IsPrint(r) {
+			return strconv.Quote(s)
+		}
+	}
+	return s
+}
+
+func (o *devOutput) Flush() error {
+	return o.writer.Flush()
+}
+
+func (o *devOutput) Close() error {
+	return o.writer.Close()
+}
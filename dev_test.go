@@ -0,0 +1,75 @@
+package sloggcp
+
+import (
+	"bytes"
+	"errors"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestNewDevHandler(t *testing.T) {
+	recordTime := time.Date(2025, 1, 1, 12, 30, 45, 123e6, time.UTC)
+	tests := []struct {
+		name  string
+		level slog.Level
+		msg   string
+		attrs []slog.Attr
+		want  string
+	}{
+		{
+			name:  "info",
+			level: slog.LevelInfo,
+			msg:   "info message",
+			attrs: []slog.Attr{
+				slog.String("foo", "bar"),
+				slog.String("quoted", "with space"),
+				slog.Int("count", 42),
+				slog.Group("group", slog.Bool("ok", true)),
+				Labels("region", "eu"),
+			},
+			want: "12:30:45.123 " + ansiBlue + "INFO" + ansiReset + ` info message count=42 foo=bar group.ok=true logging.googleapis.com/labels.region=eu quoted="with space"` + "\n",
+		},
+		{
+			name:  "error",
+			level: slog.LevelError,
+			msg:   "error message",
+			attrs: []slog.Attr{slog.Any("error", mockStackTraceError{})},
+			want:  "12:30:45.123 " + ansiRed + "ERROR" + ansiReset + " error message error=mockStackTraceError\n    stack\n",
+		},
+		{
+			name:  "error string",
+			level: slog.LevelError,
+			msg:   "error message",
+			attrs: []slog.Attr{slog.Any("error", errors.New("line 1\nline 2"))},
+			want:  "12:30:45.123 " + ansiRed + "ERROR" + ansiReset + " error message error=\"line 1\\nline 2\"\n    line 1\n    line 2\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			r := slog.NewRecord(recordTime, tt.level, tt.msg, 0)
+			r.AddAttrs(tt.attrs...)
+			if err := NewDevHandler(&buf).Handle(t.Context(), r); err != nil {
+				t.Fatal(err)
+			}
+			if got := buf.String(); got != tt.want {
+				t.Errorf("log output =\n%q\nwant\n%q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWithDevMode(t *testing.T) {
+	var buf bytes.Buffer
+	slog.New(NewHandler(&buf, WithDevMode(false))).Info("test message")
+	if !strings.HasPrefix(buf.String(), "{") {
+		t.Errorf("WithDevMode(false) output = %q, want JSON", buf.String())
+	}
+	buf.Reset()
+	slog.New(NewHandler(&buf, WithDevMode(true))).Info("test message")
+	if strings.HasPrefix(buf.String(), "{") || !strings.Contains(buf.String(), "INFO"+ansiReset+" test message") {
+		t.Errorf("WithDevMode(true) output = %q, want dev format", buf.String())
+	}
+}
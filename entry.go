@@ -0,0 +1,168 @@
+package sloggcp
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"time"
+)
+
+// Entry is a log entry for direct submission to the Cloud Logging API.
+// Its fields correspond to the fields of logging.Entry
+// from the cloud.google.com/go/logging package,
+// without this package depending on it.
+type Entry struct {
+	Timestamp      time.Time
+	Severity       string // One of the Severity constants, such as [ErrorSeverity].
+	Labels         map[string]string
+	Trace          string
+	SpanID         string
+	TraceSampled   bool
+	SourceLocation *slog.Source
+	HTTPRequest    *HTTPRequest
+	InsertID       string
+	// Payload is the JSON payload, containing all other fields,
+	// including the error reporting fields.
+	Payload json.RawMessage
+}
+
+// EntryWriter receives the entries of the handler created by [NewEntryHandler].
+// WriteEntry may be called concurrently.
+//
+// A *logging.Logger from the cloud.google.com/go/logging package can be wrapped as follows:
+//
+//	type cloudLogger struct {
+//		*logging.Logger
+//	}
+//
+//	func (l cloudLogger) WriteEntry(e sloggcp.Entry) error {
+//		l.Log(logging.Entry{
+//			Timestamp:    e.Timestamp,
+//			Severity:     logging.ParseSeverity(e.Severity),
+//			Labels:       e.Labels,
+//			Trace:        e.Trace,
+//			SpanID:       e.SpanID,
+//			TraceSampled: e.TraceSampled,
+//			InsertID:     e.InsertID,
+//			Payload:      e.Payload,
+//		})
+//		return nil
+//	}
+//
+// The embedded Flush method is then called by the handler's Flush.
+// The github.com/muhlemmer/sloggcp/cloudlogging module provides such a handler by NewClientHandler.
+type EntryWriter interface {
+	WriteEntry(e Entry) error
+}
+
+// NewEntryHandler creates a handler which passes each record as an [Entry] to w,
+// instead of writing JSON lines.
+// Severity, error reporting and all other options are handled the same as by [NewHandler].
+// The special fields with a dedicated [Entry] field are removed from the payload.
+//
+// The returned handler provides Flush() error and Close() error methods,
+// which call the respective methods of w, if implemented.
+func NewEntryHandler(w EntryWriter, options ...Option) slog.Handler {
+	c := newConfig(options)
+	return newHandler(entryOutput{w: w, timeKey: c.timeKey()}, c)
+}
+
+// RecordToEntry converts r to an [Entry], the same as the handler of [NewEntryHandler] does,
+// for custom handlers or writers which submit entries to the Cloud Logging API.
+// The attributes of r are added to groups, as if r was logged by a logger with these groups.
+// The options are applied the same as by [NewEntryHandler]; the minimum level is not checked.
+// Each call creates a new handler, so options which keep state across records,
+// such as [WithSampling] and [WithErrorDedup], have no effect: every record is converted.
+// Use the handler of [NewEntryHandler] for them.
+func RecordToEntry(ctx context.Context, r slog.Record, groups []string, options ...Option) (Entry, error) {
+	var w entryCapture
+	var h slog.Handler = NewEntryHandler(&w, options...)
+	for _, group := range groups {
+		h = h.WithGroup(group)
+	}
+	if err := h.Handle(ctx, r); err != nil {
+		return Entry{}, err
+	}
+	return w.entry, nil
+}
+
+// entryCapture is the [EntryWriter] of [RecordToEntry].
+type entryCapture struct {
+	entry Entry
+}
+
+func (c *entryCapture) WriteEntry(e Entry) error {
+	c.entry = e
+	return nil
+}
+
+type entryOutput struct {
+	w       EntryWriter
+	timeKey string // the time field, which is the Timestamp of the entry
+}
+
+func (o entryOutput) write(r *slog.Record, state *handleState) error {
+	delete(state.out, o.timeKey)
+	e := entryFromPayload(r, state.out)
+	if err := state.encoder.Encode(state.out); err != nil {
+		return fmt.Errorf("sloggcp handler: %w", err)
+	}
+	e.Payload = bytes.Clone(bytes.TrimSuffix(state.buf.Bytes(), []byte("\n")))
+	if err := o.w.WriteEntry(e); err != nil {
+		return fmt.Errorf("sloggcp handler: %w", err)
+	}
+	return nil
+}
+
+func (o entryOutput) Flush() error {
+	return flush(o.w)
+}
+
+func (o entryOutput) Close() error {
+	return closeWriter(o.w)
+}
+
+// entryFromPayload creates an [Entry] from the special fields in out,
+// which are removed from out.
+func entryFromPayload(r *slog.Record, out map[string]any) Entry {
+	e := Entry{
+		Timestamp: r.Time,
+	}
+	delete(out, TimeKey)
+	delete(out, TimestampKey)
+	if v, ok := out[SeverityKey]; ok {
+		e.Severity = severityName(v)
+		delete(out, SeverityKey)
+	}
+	if v, ok := out[LabelsKey].(map[string]string); ok {
+		e.Labels = v
+		delete(out, LabelsKey)
+	}
+	if v, ok := out[TraceKey].(string); ok {
+		e.Trace = v
+		delete(out, TraceKey)
+	}
+	if v, ok := out[SpanIDKey].(string); ok {
+		e.SpanID = v
+		delete(out, SpanIDKey)
+	}
+	if v, ok := out[TraceSampledKey].(bool); ok {
+		e.TraceSampled = v
+		delete(out, TraceSampledKey)
+	}
+	if v, ok := out[SourceLocationKey].(*gcpSource); ok {
+		e.SourceLocation = (*slog.Source)(v)
+		delete(out, SourceLocationKey)
+	}
+	if v, ok := out[HTTPRequestKey].(HTTPRequest); ok {
+		e.HTTPRequest = &v
+		delete(out, HTTPRequestKey)
+	}
+	if v, ok := out[InsertIDKey].(string); ok {
+		e.InsertID = v
+		delete(out, InsertIDKey)
+	}
+	return e
+}
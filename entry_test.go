@@ -0,0 +1,169 @@
+package sloggcp
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+type entryRecorder struct {
+	mtx     sync.Mutex
+	entries []Entry
+	flushed bool
+}
+
+func (r *entryRecorder) WriteEntry(e Entry) error {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+	r.entries = append(r.entries, e)
+	return nil
+}
+
+func (r *entryRecorder) Flush() error {
+	r.flushed = true
+	return nil
+}
+
+func TestNewEntryHandler(t *testing.T) {
+	w := new(entryRecorder)
+	h := NewEntryHandler(w,
+		WithProjectID("my-project"),
+		WithHandlerOptions(&slog.HandlerOptions{AddSource: true}),
+	)
+	ctx := trace.ContextWithSpanContext(context.Background(), trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID:    testTraceID,
+		SpanID:     testSpanID,
+		TraceFlags: trace.FlagsSampled,
+	}))
+	req := HTTPRequest{RequestMethod: "GET", Status: 500}
+
+	logger := slog.New(h).With(Labels("region", "eu"))
+	logger.ErrorContext(ctx, "error message", "error", mockReportLocationError{}, HTTP(req), InsertID("id-1"), "foo", "bar")
+
+	if len(w.entries) != 1 {
+		t.Fatalf("got %d entries, want 1", len(w.entries))
+	}
+	got := w.entries[0]
+	if got.Timestamp.IsZero() || time.Since(got.Timestamp) > time.Minute {
+		t.Errorf("Timestamp = %v, want record time", got.Timestamp)
+	}
+	if got.Severity != ErrorSeverity {
+		t.Errorf("Severity = %v, want %v", got.Severity, ErrorSeverity)
+	}
+	if want := map[string]string{"region": "eu"}; !reflect.DeepEqual(got.Labels, want) {
+		t.Errorf("Labels = %v, want %v", got.Labels, want)
+	}
+	if want := "projects/my-project/traces/4bf92f3577b34da6a3ce929d0e0e4736"; got.Trace != want {
+		t.Errorf("Trace = %v, want %v", got.Trace, want)
+	}
+	if want := "00f067aa0ba902b7"; got.SpanID != want {
+		t.Errorf("SpanID = %v, want %v", got.SpanID, want)
+	}
+	if !got.TraceSampled {
+		t.Error("TraceSampled = false, want true")
+	}
+	if got.SourceLocation == nil || got.SourceLocation.Function != "github.com/muhlemmer/sloggcp.TestNewEntryHandler" {
+		t.Errorf("SourceLocation = %v, want test function", got.SourceLocation)
+	}
+	if got.HTTPRequest == nil || *got.HTTPRequest != req {
+		t.Errorf("HTTPRequest = %v, want %v", got.HTTPRequest, req)
+	}
+	if got.InsertID != "id-1" {
+		t.Errorf("InsertID = %v, want %v", got.InsertID, "id-1")
+	}
+
+	var payload map[string]any
+	if err := json.Unmarshal(got.Payload, &payload); err != nil {
+		t.Fatalf("Failed to decode payload: %v", err)
+	}
+	delete(payload, EventTimeKey)
+	wantPayload := map[string]any{
+		ErrorReportTypeKey: ErrorReportTypeValue,
+		MessageKey:         "mockReportLocationError",
+		ErrorKey:           "mockReportLocationError",
+		ReportLocationKey: map[string]any{
+			"filePath":     mockReportLocation.FilePath,
+			"lineNumber":   float64(mockReportLocation.LineNumber),
+			"functionName": mockReportLocation.FunctionName,
+		},
+		"foo": "bar",
+	}
+	if !reflect.DeepEqual(payload, wantPayload) {
+		t.Errorf("Payload = %v, want %v", payload, wantPayload)
+	}
+
+	if err := h.(interface{ Flush() error }).Flush(); err != nil || !w.flushed {
+		t.Errorf("Flush() = %v, flushed = %v, want nil, true", err, w.flushed)
+	}
+}
+
+func TestRecordToEntry(t *testing.T) {
+	ctx := trace.ContextWithSpanContext(context.Background(), trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID:    testTraceID,
+		SpanID:     testSpanID,
+		TraceFlags: trace.FlagsSampled,
+	}))
+	recordTime := time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)
+	r := slog.NewRecord(recordTime, slog.LevelError, "error message", 0)
+	r.AddAttrs(
+		slog.String("error", "card declined"),
+		Labels("region", "eu"),
+		slog.String("order_id", "o-1"),
+	)
+
+	got, err := RecordToEntry(ctx, r, []string{"request"}, WithProjectID("my-project"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !got.Timestamp.Equal(recordTime) {
+		t.Errorf("Timestamp = %v, want %v", got.Timestamp, recordTime)
+	}
+	if got.Severity != ErrorSeverity {
+		t.Errorf("Severity = %v, want %v", got.Severity, ErrorSeverity)
+	}
+	if want := map[string]string{"region": "eu"}; !reflect.DeepEqual(got.Labels, want) {
+		t.Errorf("Labels = %v, want %v", got.Labels, want)
+	}
+	if want := "projects/my-project/traces/" + testTraceID.String(); got.Trace != want {
+		t.Errorf("Trace = %v, want %v", got.Trace, want)
+	}
+	if got.SpanID != testSpanID.String() || !got.TraceSampled {
+		t.Errorf("SpanID, TraceSampled = %v, %v, want %v, true", got.SpanID, got.TraceSampled, testSpanID)
+	}
+
+	var payload map[string]any
+	if err := json.Unmarshal(got.Payload, &payload); err != nil {
+		t.Fatalf("Failed to decode payload: %v", err)
+	}
+	want := map[string]any{
+		ErrorReportTypeKey: ErrorReportTypeValue,
+		EventTimeKey:       "2025-01-02T03:04:05Z",
+		MessageKey:         "card declined",
+		"request": map[string]any{
+			ErrorKey:   "card declined",
+			"order_id": "o-1",
+		},
+	}
+	if !reflect.DeepEqual(payload, want) {
+		t.Errorf("Payload = %v, want %v", payload, want)
+	}
+}
+
+func TestRecordToEntry_sampling(t *testing.T) {
+	r := slog.NewRecord(time.Now(), slog.LevelInfo, "test message", 0)
+	for i := range 3 {
+		got, err := RecordToEntry(t.Context(), r, nil, WithSampling(map[slog.Level]int{slog.LevelInfo: 100}))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got.Payload == nil {
+			t.Errorf("RecordToEntry() call %d returned the zero Entry, want the converted record", i)
+		}
+	}
+}
@@ -1,14 +1,22 @@
 package sloggcp
 
 import (
+	"bytes"
+	"errors"
 	"fmt"
 	"log/slog"
+	"reflect"
 	"runtime"
+	"slices"
+	"strings"
+	"time"
+	"unicode/utf8"
 
 	_ "runtime/debug"
 )
 
-// Key by which errors are retrieved from slog attributes.
+// Default key by which errors are retrieved from slog attributes.
+// It can be changed with [WithErrorKey] or [WithErrorKeys].
 // The corresponding values can be of type [string], [error], [StackTraceError] and/or [ReportLocationError].
 const (
 	ErrorKey = "error"
@@ -23,6 +31,11 @@ const (
 	FilePathKey          = "filePath"
 	LineNumberKey        = "lineNumber"
 	FunctionNameKey      = "functionName"
+	ServiceContextKey    = "serviceContext"
+	EventTimeKey         = "eventTime"
+	ErrorContextKey      = "context"
+	UserKey              = "user"
+	HTTPContextKey       = "httpRequest"
 )
 
 // StackTraceError is an error that provides a stack trace,
@@ -40,24 +53,22 @@ type ReportLocationError interface {
 	ReportLocation() *ReportLocation
 }
 
-// Make type switching easier
-type stackAndReport interface {
-	StackTraceError
-	ReportLocationError
-}
-
+// assertErrorValue determines the error message and report location of value.
+// For errors, the whole wrap chain is inspected and
+// the innermost [StackTraceError] and [ReportLocationError] are used.
 func assertErrorValue(value any) (errMsg string, reportLocation *ReportLocation) {
 	switch v := value.(type) {
-	case stackAndReport:
-		errMsg = string(v.StackTrace())
-		reportLocation = v.ReportLocation()
-	case StackTraceError:
-		errMsg = string(v.StackTrace())
-	case ReportLocationError:
-		errMsg = v.Error()
-		reportLocation = v.ReportLocation()
 	case error:
-		errMsg = v.Error()
+		if st, ok := innermostAs[StackTraceError](v); ok {
+			errMsg = string(st.StackTrace())
+		} else if s, ok := grpcStatus(v); ok {
+			errMsg = s.Message
+		} else {
+			errMsg = errorMessage(v)
+		}
+		if rl, ok := innermostAs[ReportLocationError](v); ok {
+			reportLocation = rl.ReportLocation()
+		}
 	case string:
 		errMsg = v
 	default:
@@ -67,12 +78,105 @@ func assertErrorValue(value any) (errMsg string, reportLocation *ReportLocation)
 	return errMsg, reportLocation
 }
 
+// innermostAs returns the innermost error in the chain of err which matches T,
+// using [errors.As].
+func innermostAs[T error](err error) (target T, found bool) {
+	var t T
+	for err != nil && errors.As(err, &t) {
+		target, found = t, true
+		err = errors.Unwrap(t)
+	}
+	return target, found
+}
+
 type ReportLocation struct {
 	FilePath     string `json:"filePath"`
 	LineNumber   int    `json:"lineNumber"`
 	FunctionName string `json:"functionName"`
 }
 
+// IsValid reports whether l is complete: it has a file path, a positive line number and a function name.
+// Invalid locations are omitted from error reports. IsValid returns false for a nil l.
+func (l *ReportLocation) IsValid() bool {
+	return l != nil && l.FilePath != "" && l.LineNumber > 0 && l.FunctionName != ""
+}
+
+// ServiceContext identifies the service which reported an error.
+// Error Reporting groups errors by service and version.
+type ServiceContext struct {
+	Service string `json:"service"`
+	Version string `json:"version,omitempty"`
+}
+
+// reportLocationFromPC returns the [ReportLocation] of the frame of pc.
+func reportLocationFromPC(pc uintptr) *ReportLocation {
+	frame, _ := runtime.CallersFrames([]uintptr{pc}).Next()
+	if frame.Function == "" {
+		return nil
+	}
+	return &ReportLocation{
+		FilePath:     frame.File,
+		LineNumber:   frame.Line,
+		FunctionName: frame.Function,
+	}
+}
+
+// ErrorUser returns an attribute which sets the affected user
+// in the [ErrorContextKey] object of an error report.
+func ErrorUser(id string) slog.Attr {
+	return slog.Attr{Key: ErrorContextKey, Value: slog.GroupValue(slog.String(UserKey, id))}
+}
+
+// HTTPRequestContext is the HTTP request which was affected by an error,
+// in the [ErrorContextKey] object of an error report.
+// Its fields differ from [HTTPRequest], which is the request of a log entry.
+// Zero values are omitted from the JSON output.
+// See https://cloud.google.com/error-reporting/reference/rest/v1beta1/ErrorContext#HttpRequestContext.
+type HTTPRequestContext struct {
+	Method             string `json:"method,omitempty"`
+	URL                string `json:"url,omitempty"`
+	UserAgent          string `json:"userAgent,omitempty"`
+	Referrer           string `json:"referrer,omitempty"`
+	ResponseStatusCode int    `json:"responseStatusCode,omitempty"`
+	RemoteIP           string `json:"remoteIp,omitempty"`
+}
+
+// ErrorHTTPRequest returns an attribute which sets the affected HTTP request
+// in the [ErrorContextKey] object of an error report, under [HTTPContextKey],
+// so that Error Reporting shows the affected endpoint.
+// It is independent of the [HTTPRequestKey] field set by [HTTP].
+func ErrorHTTPRequest(req HTTPRequestContext) slog.Attr {
+	return slog.Attr{Key: ErrorContextKey, Value: slog.GroupValue(slog.Any(HTTPContextKey, req))}
+}
+
+// errorContext returns the [ErrorContextKey] object of out,
+// creating it if it doesn't exist yet.
+func errorContext(out map[string]any) map[string]any {
+	ec, ok := out[ErrorContextKey].(map[string]any)
+	if !ok {
+		ec = make(map[string]any)
+		out[ErrorContextKey] = ec
+	}
+	return ec
+}
+
+// checkAndSetErrorContext merges the members of a into the [ErrorContextKey] object in out.
+// It returns false if a is not a [ErrorContextKey] group attribute.
+func checkAndSetErrorContext(a slog.Attr, out map[string]any) bool {
+	if a.Key != ErrorContextKey {
+		return false
+	}
+	v := a.Value.Resolve()
+	if v.Kind() != slog.KindGroup {
+		return false
+	}
+	ec := errorContext(out)
+	for _, member := range v.Group() {
+		ec[member.Key] = extractValue(member.Value)
+	}
+	return true
+}
+
 // NewReportLocation based on the current call stack.
 // The returned [ReportLocation] can be stored and returned
 // in a [ReportLocationError].
@@ -91,24 +195,330 @@ func NewReportLocation(skip int) *ReportLocation {
 	}
 }
 
-func checkAndSetErrorReport(a slog.Attr, out map[string]any) bool {
-	if a.Key != ErrorKey {
-		return false
+// NewLocatedError wraps err in a [ReportLocationError],
+// with the report location based on the current call stack.
+// The skip parameter is the number of stack frames to skip
+// (0 identifies the caller of NewLocatedError).
+// The returned error unwraps to err. If err is nil, nil is returned.
+func NewLocatedError(err error, skip int) ReportLocationError {
+	if err == nil {
+		return nil
 	}
-	value := a.Value.Any()
+	return &locatedError{
+		err:      err,
+		location: NewReportLocation(skip + 1),
+	}
+}
+
+type locatedError struct {
+	err      error
+	location *ReportLocation
+}
+
+func (e *locatedError) Error() string {
+	return e.err.Error()
+}
+
+func (e *locatedError) Unwrap() error {
+	return e.err
+}
+
+func (e *locatedError) ReportLocation() *ReportLocation {
+	return e.location
+}
+
+// errorPriority returns the priority of a as error attribute, where lower values take precedence,
+// or -1 if a is not an error attribute.
+// Values created by [Err] take precedence over the configured error keys,
+// which are prioritized by their index.
+func (c *config) errorPriority(a slog.Attr) int {
+	if _, ok := a.Value.Any().(primaryError); ok {
+		return 0
+	}
+	if i := slices.Index(c.errorKeys, a.Key); i >= 0 {
+		return i + 1
+	}
+	return -1
+}
+
+// Err returns an attribute with key [ErrorKey], which marks err as the primary error of the record.
+// The value is recognized by the handler regardless of the attribute key,
+// and takes precedence over other error attributes.
+// To use a different key, copy the value:
+//
+//	slog.Attr{Key: "cause", Value: sloggcp.Err(err).Value}
+//
+// If err is nil, the zero [slog.Attr] is returned, which is dropped by the handler.
+func Err(err error) slog.Attr {
+	if err == nil {
+		return slog.Attr{}
+	}
+	return slog.Any(ErrorKey, primaryError{err})
+}
+
+// primaryError marks an error created by [Err].
+type primaryError struct {
+	error
+}
+
+func (e primaryError) Unwrap() error {
+	return e.error
+}
+
+// setErrorReport sets the error report attributes in out, from the error attribute a.
+// The error value is set in group, which is out for top-level error attributes.
+func (h *handler) setErrorReport(r *slog.Record, a slog.Attr, group, out map[string]any) {
+	value := errorAttrValue(a)
 	errMsg, reportLocation := assertErrorValue(value)
-	out[ErrorReportTypeKey] = ErrorReportTypeValue
+	hasStack := hasStackTrace(value)
+	if h.config.recordStack && r.PC != 0 && !hasStack {
+		errMsg += "\n\n" + string(stackFromPC(r.PC))
+	}
+	errMsg = truncateStack(errMsg, h.config.maxStackSize)
+	if _, hasSource := out[SourceLocationKey]; h.config.errorSource && !hasSource {
+		h.config.setSource(r, out)
+	}
+	if _, hasSource := out[SourceLocationKey]; h.config.recordLocation && !reportLocation.IsValid() && !hasStack && !hasSource && r.PC != 0 {
+		reportLocation = reportLocationFromPC(r.PC)
+	}
+	if h.config.preserveMessage && r.Message != "" {
+		errMsg = r.Message + ": " + errMsg
+	}
+	out[ErrorReportTypeKey] = h.config.errorReportType
+	out[EventTimeKey] = h.config.eventTime(r.Time)
 	out[MessageKey] = errMsg
-	out[ErrorKey] = value
-	if reportLocation != nil {
-		out[ReportLocationKey] = reportLocation
+	if reportLocation.IsValid() {
+		if h.config.nestedReportContext {
+			errorContext(out)[ReportLocationKey] = reportLocation
+		} else {
+			out[ReportLocationKey] = reportLocation
+		}
 	}
+	if h.config.serviceContext != nil {
+		out[ServiceContextKey] = h.config.serviceContext
+	}
+	group[a.Key] = errorValue(value)
+}
+
+// DefaultMaxStackSize is the default limit of [WithMaxStackSize].
+// It keeps error reports well below the 256 KB size limit of a Cloud Logging entry.
+const DefaultMaxStackSize = 16 << 10
+
+// TruncatedMarker ends error report messages which are cut by [WithMaxStackSize].
+const TruncatedMarker = "...[truncated]"
+
+// truncateStack cuts msg to at most size bytes, including [TruncatedMarker],
+// unless size is smaller than the marker.
+// The cut is made after the last complete line, or at a rune boundary for a single long line.
+func truncateStack(msg string, size int) string {
+	if size <= 0 || len(msg) <= size {
+		return msg
+	}
+	n := max(size-len(TruncatedMarker), 0)
+	if i := strings.LastIndexByte(msg[:n], '\n'); i >= 0 {
+		n = i + 1
+	} else {
+		for n > 0 && !utf8.RuneStart(msg[n]) {
+			n--
+		}
+	}
+	return msg[:n] + TruncatedMarker
+}
+
+// errorAttrValue returns the value of the error attribute a,
+// with the marker of [Err] removed.
+func errorAttrValue(a slog.Attr) any {
+	value := a.Value.Any()
+	if pe, ok := value.(primaryError); ok {
+		return pe.error
+	}
+	return value
+}
+
+// reportsError reports whether records of level are promoted to error reports.
+func (c *config) reportsError(level slog.Level) bool {
+	return c.errorReportMinLevel == nil || level >= c.errorReportMinLevel.Level()
+}
+
+// errorValue returns the structured representation of an error attribute value.
+func errorValue(value any) any {
 	switch v := value.(type) {
 	case slog.LogValuer:
-		out[ErrorKey] = extractValue(v.LogValue())
+		return extractValue(v.LogValue())
 	case error:
-		out[ErrorKey] = v.Error()
+		if s, ok := grpcStatus(v); ok {
+			return s
+		}
+		if m, ok := v.(multiError); ok {
+			return joinedErrors(m.Unwrap())
+		}
+		return v.Error()
 	}
+	return value
+}
 
-	return true
+// ErrorCausesKey is the field for the causes of an error, added by [WithErrorChain].
+const ErrorCausesKey = "causes"
+
+// errorCauses returns the Error() strings of the errors wrapped by value,
+// as returned by successive [errors.Unwrap] calls, deepest last.
+func errorCauses(value any) []string {
+	err, ok := value.(error)
+	if !ok {
+		return nil
+	}
+	var causes []string
+	for err = errors.Unwrap(err); err != nil; err = errors.Unwrap(err) {
+		causes = append(causes, err.Error())
+	}
+	return causes
+}
+
+// multiError is implemented by errors created with [errors.Join]
+// or [fmt.Errorf] with multiple %w verbs.
+type multiError interface {
+	error
+	Unwrap() []error
+}
+
+// joinedError is the JSON representation of a single error of a [multiError].
+type joinedError struct {
+	Message    string `json:"message"`
+	StackTrace string `json:"stackTrace,omitempty"`
+}
+
+func joinedErrors(errs []error) []joinedError {
+	out := make([]joinedError, 0, len(errs))
+	for _, err := range errs {
+		if err == nil {
+			continue
+		}
+		je := joinedError{Message: err.Error()}
+		if st, ok := innermostAs[StackTraceError](err); ok {
+			je.StackTrace = string(st.StackTrace())
+		}
+		out = append(out, je)
+	}
+	return out
+}
+
+// eventTime formats t for the [EventTimeKey] field.
+// A zero t is replaced by the current time.
+func (c *config) eventTime(t time.Time) string {
+	return c.zone(c.timeOrNow(t)).Format(time.RFC3339Nano)
+}
+
+// timeOrNow returns t, or the current time of the clock set by [WithClock] if t is zero.
+func (c *config) timeOrNow(t time.Time) time.Time {
+	if t.IsZero() {
+		return c.clock()
+	}
+	return t
+}
+
+const maxStackDepth = 64
+
+// stackFromPC renders a stack trace in the format of [debug.Stack],
+// starting at the frame of pc.
+// The frames are taken from the current call stack,
+// if pc is part of it. Otherwise only the frame of pc is rendered.
+func stackFromPC(pc uintptr) []byte {
+	pcs := make([]uintptr, maxStackDepth)
+	pcs = pcs[:runtime.Callers(2, pcs)]
+	start := slices.Index(pcs, pc)
+	if start < 0 {
+		pcs = []uintptr{pc}
+	} else {
+		pcs = pcs[start:]
+	}
+	return formatStack(pcs)
+}
+
+// formatStack renders the frames of pcs, as returned by [runtime.Callers],
+// in the format of [debug.Stack].
+func formatStack(pcs []uintptr) []byte {
+	var buf bytes.Buffer
+	buf.Write(goroutineHeader())
+	frames := runtime.CallersFrames(pcs)
+	for {
+		frame, more := frames.Next()
+		if frame.Function != "runtime.goexit" {
+			fmt.Fprintf(&buf, "%s(...)\n\t%s:%d\n", frame.Function, frame.File, frame.Line)
+		}
+		if !more {
+			break
+		}
+	}
+	return buf.Bytes()
+}
+
+// hasStackTrace reports whether value provides its own stack trace.
+func hasStackTrace(value any) bool {
+	err, ok := value.(error)
+	if !ok {
+		return false
+	}
+	if _, ok := innermostAs[StackTraceError](err); ok {
+		return true
+	}
+	_, ok = framesStackTrace(err)
+	return ok
+}
+
+// errorMessage returns the error string of err.
+// If an error in the chain of err provides a stack trace in the form of github.com/pkg/errors,
+// the innermost rendered stack trace is appended.
+func errorMessage(err error) string {
+	if pcs, ok := framesStackTrace(err); ok {
+		return err.Error() + "\n\n" + string(formatStack(pcs))
+	}
+	return err.Error()
+}
+
+// framesStackTrace returns the innermost frames of the chain of err.
+func framesStackTrace(err error) (pcs []uintptr, found bool) {
+	for ; err != nil; err = errors.Unwrap(err) {
+		if frames, ok := errorFrames(err); ok {
+			pcs, found = frames, true
+		}
+	}
+	return pcs, found
+}
+
+// errorFrames detects a StackTrace method as implemented by
+// github.com/pkg/errors, without depending on that module.
+// Such a method returns a slice of frames, where each frame is a program counter
+// with an underlying uintptr type.
+func errorFrames(err error) ([]uintptr, bool) {
+	method := reflect.ValueOf(err).MethodByName("StackTrace")
+	if !method.IsValid() {
+		return nil, false
+	}
+	typ := method.Type()
+	if typ.NumIn() != 0 || typ.NumOut() != 1 {
+		return nil, false
+	}
+	if out := typ.Out(0); out.Kind() != reflect.Slice || out.Elem().Kind() != reflect.Uintptr {
+		return nil, false
+	}
+	frames := method.Call(nil)[0]
+	if frames.Len() == 0 {
+		return nil, false
+	}
+	pcs := make([]uintptr, frames.Len())
+	for i := range pcs {
+		pcs[i] = uintptr(frames.Index(i).Uint())
+	}
+	return pcs, true
+}
+
+// goroutineHeader returns the first line of the [runtime.Stack] output,
+// such as "goroutine 1 [running]:\n".
+func goroutineHeader() []byte {
+	buf := make([]byte, 64)
+	buf = buf[:runtime.Stack(buf, false)]
+	if i := bytes.IndexByte(buf, '\n'); i >= 0 {
+		return buf[:i+1]
+	}
+	return []byte("goroutine 1 [running]:\n")
 }
@@ -1,12 +1,17 @@
 package sloggcp
 
 import (
+	"bytes"
+	"context"
+	"encoding/json"
 	"errors"
+	"fmt"
 	"log/slog"
 	"reflect"
 	"runtime"
 	"strings"
 	"testing"
+	"time"
 )
 
 func Test_assertErrorValue(t *testing.T) {
@@ -139,6 +144,102 @@ func (m mockReportLocationError) ReportLocation() *ReportLocation {
 	return &mockReportLocation
 }
 
+func TestReportLocation_IsValid(t *testing.T) {
+	tests := []struct {
+		name string
+		l    *ReportLocation
+		want bool
+	}{
+		{"nil", nil, false},
+		{"zero", &ReportLocation{}, false},
+		{"no function", &ReportLocation{FilePath: "file.go", LineNumber: 42}, false},
+		{"line 0", &ReportLocation{FilePath: "file.go", FunctionName: "package.function"}, false},
+		{"complete", &mockReportLocation, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.l.IsValid(); got != tt.want {
+				t.Errorf("IsValid() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+type locationError struct {
+	location *ReportLocation
+}
+
+func (e locationError) Error() string {
+	return "locationError"
+}
+
+func (e locationError) ReportLocation() *ReportLocation {
+	return e.location
+}
+
+func TestHandler_invalidReportLocation(t *testing.T) {
+	tests := []struct {
+		name     string
+		location *ReportLocation
+		want     bool
+	}{
+		{"zero", &ReportLocation{}, false},
+		{"complete", &mockReportLocation, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			logger := slog.New(NewHandler(&buf))
+			logger.Error("error message", "error", locationError{tt.location})
+
+			var got map[string]any
+			if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+				t.Fatalf("Failed to decode log output: %v", err)
+			}
+			if _, ok := got[ReportLocationKey]; ok != tt.want {
+				t.Errorf("%s present = %v, want %v: %s", ReportLocationKey, ok, tt.want, buf.String())
+			}
+		})
+	}
+}
+
+func newLocatedErrorHelper(err error) ReportLocationError {
+	return NewLocatedError(err, 1)
+}
+
+func TestNewLocatedError(t *testing.T) {
+	errBase := errors.New("oops")
+	err := NewLocatedError(errBase, 0)
+	_, _, wantLine, _ := runtime.Caller(0)
+	wantLine-- // previous line
+
+	if err.Error() != "oops" {
+		t.Errorf("Error() = %q, want %q", err.Error(), "oops")
+	}
+	if !errors.Is(err, errBase) {
+		t.Error("NewLocatedError() does not unwrap to err")
+	}
+	loc := err.ReportLocation()
+	if loc == nil || loc.LineNumber != wantLine || loc.FunctionName != "github.com/muhlemmer/sloggcp.TestNewLocatedError" {
+		t.Errorf("ReportLocation() = %v, want line %d of test function", loc, wantLine)
+	}
+
+	err = newLocatedErrorHelper(errBase)
+	_, _, wantLine, _ = runtime.Caller(0)
+	if loc := err.ReportLocation(); loc == nil || loc.LineNumber != wantLine-1 {
+		t.Errorf("ReportLocation() with skip = %v, want line %d", loc, wantLine-1)
+	}
+
+	_, gotLocation := assertErrorValue(fmt.Errorf("wrapped: %w", NewLocatedError(errBase, 0)))
+	if gotLocation == nil || gotLocation.FunctionName != "github.com/muhlemmer/sloggcp.TestNewLocatedError" {
+		t.Errorf("assertErrorValue() reportLocation = %v, want location of test function", gotLocation)
+	}
+
+	if err := NewLocatedError(nil, 0); err != nil {
+		t.Errorf("NewLocatedError(nil) = %v, want nil", err)
+	}
+}
+
 type mockStackTraceError struct{}
 
 func (m mockStackTraceError) Error() string {
@@ -173,3 +274,1263 @@ func (m mockStackAndReportValuer) LogValue() slog.Value {
 		slog.Int("key2", 42),
 	)
 }
+
+type mockValuerError struct{}
+
+func (m mockValuerError) Error() string {
+	return "mockValuerError"
+}
+
+func (m mockValuerError) LogValue() slog.Value {
+	return slog.GroupValue(
+		slog.String("key1", "value1"),
+		slog.Int("key2", 42),
+	)
+}
+
+func TestHandler_logValuerError(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(NewHandler(&buf))
+	logger.Error("error message", "error", mockValuerError{})
+
+	var got expectSchema
+	if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+		t.Fatalf("Failed to decode log output: %v", err)
+	}
+	if got.Type != ErrorReportTypeValue {
+		t.Errorf("@type = %q, want %q", got.Type, ErrorReportTypeValue)
+	}
+	if got.Message != "mockValuerError" {
+		t.Errorf("message = %q, want %q", got.Message, "mockValuerError")
+	}
+	want := map[string]any{"key1": "value1", "key2": float64(42)}
+	if !reflect.DeepEqual(got.Error, want) {
+		t.Errorf("error = %v, want %v", got.Error, want)
+	}
+	if got.ReportLocation != (ReportLocation{}) {
+		t.Errorf("reportLocation = %v, want none", got.ReportLocation)
+	}
+}
+
+func TestHandler_recordStack(t *testing.T) {
+	tests := []struct {
+		name      string
+		enable    bool
+		value     any
+		wantStack bool
+	}{
+		{
+			name:      "disabled",
+			enable:    false,
+			value:     errors.New("oops"),
+			wantStack: false,
+		},
+		{
+			name:      "plain error",
+			enable:    true,
+			value:     errors.New("oops"),
+			wantStack: true,
+		},
+		{
+			name:      "StackTraceError",
+			enable:    true,
+			value:     mockStackTraceError{},
+			wantStack: false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			logger := slog.New(NewErrorReportingHandler(&buf, nil, WithRecordStack(tt.enable)))
+			logger.Error("error message", "error", tt.value)
+
+			var got expectSchema
+			if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+				t.Fatalf("Failed to decode log output: %v", err)
+			}
+			if !tt.wantStack {
+				if strings.Contains(got.Message, "goroutine ") {
+					t.Errorf("message = %q, want no stack trace", got.Message)
+				}
+				return
+			}
+			errMsg, stack, ok := strings.Cut(got.Message, "\n\n")
+			if !ok {
+				t.Fatalf("message = %q, want stack trace", got.Message)
+			}
+			if errMsg != "oops" {
+				t.Errorf("message error = %q, want %q", errMsg, "oops")
+			}
+			lines := strings.Split(stack, "\n")
+			if !strings.HasPrefix(lines[0], "goroutine ") || !strings.HasSuffix(lines[0], "[running]:") {
+				t.Errorf("stack header = %q, want goroutine header", lines[0])
+			}
+			const wantFunc = "github.com/muhlemmer/sloggcp.TestHandler_recordStack.func1(...)"
+			if lines[1] != wantFunc {
+				t.Errorf("first frame = %q, want %q", lines[1], wantFunc)
+			}
+			if strings.Contains(stack, "log/slog.") {
+				t.Errorf("stack contains slog frames:\n%s", stack)
+			}
+		})
+	}
+}
+
+// returnedPC returns a program counter of a frame which is no longer on the stack.
+func returnedPC() uintptr {
+	var pcs [1]uintptr
+	runtime.Callers(1, pcs[:])
+	return pcs[0]
+}
+
+func Test_stackFromPC_notOnStack(t *testing.T) {
+	stack := string(stackFromPC(returnedPC()))
+	if n := strings.Count(stack, "\n\t"); n != 1 {
+		t.Errorf("stackFromPC() rendered %d frames, want 1:\n%s", n, stack)
+	}
+	if !strings.Contains(stack, "sloggcp.returnedPC(...)") {
+		t.Errorf("stackFromPC() = %q, want returnedPC frame", stack)
+	}
+}
+
+// mockFrame and mockFrames mimic the github.com/pkg/errors Frame and StackTrace types.
+type mockFrame uintptr
+
+type mockFrames []mockFrame
+
+type mockPkgError struct {
+	frames mockFrames
+}
+
+func newMockPkgError() mockPkgError {
+	pcs := make([]uintptr, 1)
+	runtime.Callers(2, pcs)
+	frames := make(mockFrames, len(pcs))
+	for i, pc := range pcs {
+		frames[i] = mockFrame(pc)
+	}
+	return mockPkgError{frames: frames}
+}
+
+func (m mockPkgError) Error() string {
+	return "mockPkgError"
+}
+
+func (m mockPkgError) StackTrace() mockFrames {
+	return m.frames
+}
+
+type mockWrongStackTrace struct{}
+
+func (mockWrongStackTrace) Error() string {
+	return "mockWrongStackTrace"
+}
+
+func (mockWrongStackTrace) StackTrace() []string {
+	return []string{"foo"}
+}
+
+func Test_errorMessage(t *testing.T) {
+	pkgErr := newMockPkgError()
+	_, _, wantLine, _ := runtime.Caller(0)
+	wantLine--
+
+	got := errorMessage(pkgErr)
+	errMsg, stack, ok := strings.Cut(got, "\n\n")
+	if !ok {
+		t.Fatalf("errorMessage() = %q, want stack trace", got)
+	}
+	if errMsg != "mockPkgError" {
+		t.Errorf("errorMessage() error = %q, want %q", errMsg, "mockPkgError")
+	}
+	wantFrame := fmt.Sprintf("github.com/muhlemmer/sloggcp.Test_errorMessage(...)\n\t%s:%d\n", testFile(t), wantLine)
+	if !strings.HasPrefix(stack, "goroutine ") || !strings.HasSuffix(stack, wantFrame) {
+		t.Errorf("errorMessage() stack =\n%s\nwant frame\n%s", stack, wantFrame)
+	}
+
+	for _, err := range []error{errors.New("oops"), mockWrongStackTrace{}, mockPkgError{}} {
+		if got := errorMessage(err); got != err.Error() {
+			t.Errorf("errorMessage() = %q, want %q", got, err.Error())
+		}
+	}
+}
+
+func testFile(t *testing.T) string {
+	t.Helper()
+	_, file, _, _ := runtime.Caller(1)
+	return file
+}
+
+// mockWrapError wraps an error and provides a report location.
+type mockWrapError struct {
+	err      error
+	location *ReportLocation
+}
+
+func (m mockWrapError) Error() string {
+	return "located: " + m.err.Error()
+}
+
+func (m mockWrapError) Unwrap() error {
+	return m.err
+}
+
+func (m mockWrapError) ReportLocation() *ReportLocation {
+	return m.location
+}
+
+func Test_assertErrorValue_chain(t *testing.T) {
+	innerLocation := &ReportLocation{FilePath: "inner.go", LineNumber: 1, FunctionName: "inner"}
+	outerLocation := &ReportLocation{FilePath: "outer.go", LineNumber: 2, FunctionName: "outer"}
+
+	tests := []struct {
+		name               string
+		value              error
+		wantErrMsg         string
+		wantReportLocation *ReportLocation
+	}{
+		{
+			name: "location in the middle",
+			value: fmt.Errorf("outer: %w", mockWrapError{
+				err:      errors.New("inner"),
+				location: innerLocation,
+			}),
+			wantErrMsg:         "outer: located: inner",
+			wantReportLocation: innerLocation,
+		},
+		{
+			name: "innermost location wins",
+			value: fmt.Errorf("outer: %w", mockWrapError{
+				err: mockWrapError{
+					err:      errors.New("inner"),
+					location: innerLocation,
+				},
+				location: outerLocation,
+			}),
+			wantErrMsg:         "outer: located: located: inner",
+			wantReportLocation: innerLocation,
+		},
+		{
+			name:               "stack trace in the chain",
+			value:              fmt.Errorf("outer: %w", fmt.Errorf("middle: %w", mockStackAndReport{})),
+			wantErrMsg:         "stack",
+			wantReportLocation: &mockReportLocation,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotErrMsg, gotReportLocation := assertErrorValue(tt.value)
+			if gotErrMsg != tt.wantErrMsg {
+				t.Errorf("assertErrorValue() = %v, want %v", gotErrMsg, tt.wantErrMsg)
+			}
+			if !reflect.DeepEqual(gotReportLocation, tt.wantReportLocation) {
+				t.Errorf("assertErrorValue() = %v, want %v", gotReportLocation, tt.wantReportLocation)
+			}
+		})
+	}
+}
+
+func TestHandler_serviceContext(t *testing.T) {
+	tests := []struct {
+		name    string
+		options []Option
+		log     func(logger *slog.Logger)
+		want    any
+	}{
+		{
+			name: "unset",
+			log: func(logger *slog.Logger) {
+				logger.Error("error message", "error", "oops")
+			},
+			want: nil,
+		},
+		{
+			name:    "error record",
+			options: []Option{WithServiceContext("my-service", "v1.2.3")},
+			log: func(logger *slog.Logger) {
+				logger.Error("error message", "error", "oops")
+			},
+			want: map[string]any{
+				"service": "my-service",
+				"version": "v1.2.3",
+			},
+		},
+		{
+			name:    "without version",
+			options: []Option{WithServiceContext("my-service", "")},
+			log: func(logger *slog.Logger) {
+				logger.Error("error message", "error", "oops")
+			},
+			want: map[string]any{
+				"service": "my-service",
+			},
+		},
+		{
+			name:    "non error record",
+			options: []Option{WithServiceContext("my-service", "v1.2.3")},
+			log: func(logger *slog.Logger) {
+				logger.Info("info message")
+			},
+			want: nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			tt.log(slog.New(NewErrorReportingHandler(&buf, nil, tt.options...)))
+
+			var got map[string]any
+			if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+				t.Fatalf("Failed to decode log output: %v", err)
+			}
+			if !reflect.DeepEqual(got[ServiceContextKey], tt.want) {
+				t.Errorf("%s = %v, want %v", ServiceContextKey, got[ServiceContextKey], tt.want)
+			}
+		})
+	}
+}
+
+func TestHandler_errorUser(t *testing.T) {
+	tests := []struct {
+		name string
+		log  func(logger *slog.Logger)
+		want map[string]any
+	}{
+		{
+			name: "record attribute",
+			log: func(logger *slog.Logger) {
+				logger.Error("fail", "error", mockReportLocationError{}, ErrorUser("u123"))
+			},
+			want: map[string]any{
+				"user": "u123",
+			},
+		},
+		{
+			name: "WithAttrs",
+			log: func(logger *slog.Logger) {
+				logger.With(ErrorUser("u123")).Error("fail", "error", mockReportLocationError{})
+			},
+			want: map[string]any{
+				"user": "u123",
+			},
+		},
+		{
+			name: "merged context",
+			log: func(logger *slog.Logger) {
+				logger.With(slog.Group(ErrorContextKey, slog.String("foo", "bar"))).
+					Error("fail", "error", mockReportLocationError{}, ErrorUser("u123"))
+			},
+			want: map[string]any{
+				"foo":  "bar",
+				"user": "u123",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			tt.log(slog.New(NewErrorReportingHandler(&buf, nil)))
+
+			var got struct {
+				Context        map[string]any `json:"context"`
+				ReportLocation ReportLocation `json:"reportLocation"`
+			}
+			if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+				t.Fatalf("Failed to decode log output: %v", err)
+			}
+			if !reflect.DeepEqual(got.Context, tt.want) {
+				t.Errorf("%s = %v, want %v", ErrorContextKey, got.Context, tt.want)
+			}
+			if got.ReportLocation != mockReportLocation {
+				t.Errorf("%s = %v, want %v", ReportLocationKey, got.ReportLocation, mockReportLocation)
+			}
+		})
+	}
+}
+
+func TestErrorHTTPRequest(t *testing.T) {
+	var buf bytes.Buffer
+	slog.New(NewErrorReportingHandler(&buf, nil)).Error("fail",
+		"error", mockReportLocationError{},
+		ErrorUser("u123"),
+		ErrorHTTPRequest(HTTPRequestContext{
+			Method:             "POST",
+			URL:                "https://example.com/api?q=1",
+			UserAgent:          "curl/8.0",
+			Referrer:           "https://example.com/",
+			ResponseStatusCode: 500,
+			RemoteIP:           "192.0.2.1",
+		}),
+		HTTP(HTTPRequest{RequestMethod: "POST", Status: 500}),
+	)
+
+	var got map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+		t.Fatalf("Failed to decode log output: %v", err)
+	}
+	// field names of the Error Reporting HttpRequestContext
+	want := map[string]any{
+		"user": "u123",
+		"httpRequest": map[string]any{
+			"method":             "POST",
+			"url":                "https://example.com/api?q=1",
+			"userAgent":          "curl/8.0",
+			"referrer":           "https://example.com/",
+			"responseStatusCode": float64(500),
+			"remoteIp":           "192.0.2.1",
+		},
+	}
+	if !reflect.DeepEqual(got[ErrorContextKey], want) {
+		t.Errorf("%s = %v, want %v", ErrorContextKey, got[ErrorContextKey], want)
+	}
+	// the log entry request keeps the Cloud Logging field names
+	wantHTTP := map[string]any{"requestMethod": "POST", "status": float64(500)}
+	if !reflect.DeepEqual(got[HTTPRequestKey], wantHTTP) {
+		t.Errorf("%s = %v, want %v", HTTPRequestKey, got[HTTPRequestKey], wantHTTP)
+	}
+}
+
+func TestHTTPRequestContext_omitempty(t *testing.T) {
+	got, err := json.Marshal(HTTPRequestContext{Method: "GET"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `{"method":"GET"}`; string(got) != want {
+		t.Errorf("json.Marshal() = %s, want %s", got, want)
+	}
+}
+
+func TestHandler_nestedReportContext(t *testing.T) {
+	locationJSON := map[string]any{
+		"filePath":     mockReportLocation.FilePath,
+		"lineNumber":   float64(mockReportLocation.LineNumber),
+		"functionName": mockReportLocation.FunctionName,
+	}
+	tests := []struct {
+		name        string
+		nested      bool
+		wantTop     any
+		wantContext any
+	}{
+		{
+			name:    "flat",
+			nested:  false,
+			wantTop: locationJSON,
+			wantContext: map[string]any{
+				"user": "u123",
+			},
+		},
+		{
+			name:    "nested",
+			nested:  true,
+			wantTop: nil,
+			wantContext: map[string]any{
+				"user":           "u123",
+				"reportLocation": locationJSON,
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, userFirst := range []bool{false, true} {
+				var buf bytes.Buffer
+				logger := slog.New(NewErrorReportingHandler(&buf, nil, WithNestedReportContext(tt.nested)))
+				if userFirst {
+					logger.Error("fail", ErrorUser("u123"), "error", mockReportLocationError{})
+				} else {
+					logger.Error("fail", "error", mockReportLocationError{}, ErrorUser("u123"))
+				}
+
+				var got map[string]any
+				if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+					t.Fatalf("Failed to decode log output: %v", err)
+				}
+				if !reflect.DeepEqual(got[ReportLocationKey], tt.wantTop) {
+					t.Errorf("%s = %v, want %v", ReportLocationKey, got[ReportLocationKey], tt.wantTop)
+				}
+				if !reflect.DeepEqual(got[ErrorContextKey], tt.wantContext) {
+					t.Errorf("%s = %v, want %v", ErrorContextKey, got[ErrorContextKey], tt.wantContext)
+				}
+			}
+		})
+	}
+}
+
+func TestHandler_eventTime(t *testing.T) {
+	recordTime := time.Date(2025, 1, 2, 3, 4, 5, 6, time.UTC)
+	tests := []struct {
+		name       string
+		recordTime time.Time
+		level      slog.Level
+		attrs      []slog.Attr
+		wantTime   func(t *testing.T, got time.Time)
+	}{
+		{
+			name:       "record time",
+			recordTime: recordTime,
+			level:      slog.LevelError,
+			attrs:      []slog.Attr{slog.String(ErrorKey, "oops")},
+			wantTime: func(t *testing.T, got time.Time) {
+				if !got.Equal(recordTime) {
+					t.Errorf("%s = %v, want %v", EventTimeKey, got, recordTime)
+				}
+			},
+		},
+		{
+			name:  "zero record time",
+			level: slog.LevelError,
+			attrs: []slog.Attr{slog.String(ErrorKey, "oops")},
+			wantTime: func(t *testing.T, got time.Time) {
+				if time.Since(got) > time.Minute {
+					t.Errorf("%s = %v, want current time", EventTimeKey, got)
+				}
+			},
+		},
+		{
+			name:       "no error",
+			recordTime: recordTime,
+			level:      slog.LevelError,
+			wantTime:   nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			h := NewErrorReportingHandler(&buf, nil)
+			r := slog.NewRecord(tt.recordTime, tt.level, "error message", 0)
+			r.AddAttrs(tt.attrs...)
+			if err := h.Handle(t.Context(), r); err != nil {
+				t.Fatal(err)
+			}
+
+			var got map[string]any
+			if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+				t.Fatalf("Failed to decode log output: %v", err)
+			}
+			value, ok := got[EventTimeKey]
+			if tt.wantTime == nil {
+				if ok {
+					t.Errorf("%s = %v, want omitted", EventTimeKey, value)
+				}
+				return
+			}
+			s, _ := value.(string)
+			eventTime, err := time.Parse(time.RFC3339Nano, s)
+			if err != nil {
+				t.Fatalf("%s = %v, parse error: %v", EventTimeKey, value, err)
+			}
+			tt.wantTime(t, eventTime)
+		})
+	}
+}
+
+func TestWithClock(t *testing.T) {
+	now := time.Date(2025, 1, 2, 3, 4, 5, 6, time.UTC)
+	var buf bytes.Buffer
+	h := NewHandler(&buf, WithClock(func() time.Time { return now }))
+	r := slog.NewRecord(time.Time{}, slog.LevelError, "error message", 0)
+	r.AddAttrs(slog.String(ErrorKey, "oops"))
+	if err := h.Handle(t.Context(), r); err != nil {
+		t.Fatal(err)
+	}
+
+	var got map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+		t.Fatalf("Failed to decode log output: %v", err)
+	}
+	if want := "2025-01-02T03:04:05.000000006Z"; got[EventTimeKey] != want {
+		t.Errorf("%s = %v, want %v", EventTimeKey, got[EventTimeKey], want)
+	}
+}
+
+func TestHandler_errorKey(t *testing.T) {
+	tests := []struct {
+		name    string
+		options []Option
+		log     func(logger *slog.Logger)
+		want    map[string]any
+	}{
+		{
+			name: "default key",
+			log: func(logger *slog.Logger) {
+				logger.Error("error message", "err", errors.New("oops"))
+			},
+			want: map[string]any{
+				MessageKey:  "error message",
+				SeverityKey: ErrorSeverity,
+				"err":       "oops",
+			},
+		},
+		{
+			name:    "custom key",
+			options: []Option{WithErrorKey("err")},
+			log: func(logger *slog.Logger) {
+				logger.Error("error message", "err", errors.New("oops"))
+			},
+			want: map[string]any{
+				ErrorReportTypeKey: ErrorReportTypeValue,
+				MessageKey:         "oops",
+				SeverityKey:        ErrorSeverity,
+				"err":              "oops",
+			},
+		},
+		{
+			name:    "custom key from WithAttrs",
+			options: []Option{WithErrorKey("err")},
+			log: func(logger *slog.Logger) {
+				logger.With("err", "oops").Error("error message")
+			},
+			want: map[string]any{
+				ErrorReportTypeKey: ErrorReportTypeValue,
+				MessageKey:         "oops",
+				SeverityKey:        ErrorSeverity,
+				"err":              "oops",
+			},
+		},
+		{
+			name:    "multiple keys, priority order",
+			options: []Option{WithErrorKeys("error", "err", "cause")},
+			log: func(logger *slog.Logger) {
+				logger.Error("error message", "cause", "cause error", "err", "err error")
+			},
+			want: map[string]any{
+				ErrorReportTypeKey: ErrorReportTypeValue,
+				MessageKey:         "err error",
+				SeverityKey:        ErrorSeverity,
+				"err":              "err error",
+				"cause":            "cause error",
+			},
+		},
+		{
+			name:    "multiple keys, priority over WithAttrs",
+			options: []Option{WithErrorKeys("error", "err", "cause")},
+			log: func(logger *slog.Logger) {
+				logger.With("error", "with error").Error("error message", "cause", "cause error")
+			},
+			want: map[string]any{
+				ErrorReportTypeKey: ErrorReportTypeValue,
+				MessageKey:         "with error",
+				SeverityKey:        ErrorSeverity,
+				"error":            "with error",
+				"cause":            "cause error",
+			},
+		},
+		{
+			name:    "same key, last wins",
+			options: []Option{WithErrorKeys("error", "err")},
+			log: func(logger *slog.Logger) {
+				logger.With("err", "first").Error("error message", "err", "second")
+			},
+			want: map[string]any{
+				ErrorReportTypeKey: ErrorReportTypeValue,
+				MessageKey:         "second",
+				SeverityKey:        ErrorSeverity,
+				"err":              "second",
+			},
+		},
+		{
+			name:    "default key not detected",
+			options: []Option{WithErrorKey("err")},
+			log: func(logger *slog.Logger) {
+				logger.Error("error message", "error", "oops")
+			},
+			want: map[string]any{
+				MessageKey:  "error message",
+				SeverityKey: ErrorSeverity,
+				ErrorKey:    "oops",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			tt.log(slog.New(NewHandler(&buf, tt.options...)))
+
+			var got map[string]any
+			if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+				t.Fatalf("Failed to decode log output: %v", err)
+			}
+			delete(got, TimeKey)
+			delete(got, EventTimeKey)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("log output = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHandler_preserveMessage(t *testing.T) {
+	tests := []struct {
+		name    string
+		enable  bool
+		message string
+		value   any
+		want    string
+	}{
+		{
+			name:    "disabled",
+			enable:  false,
+			message: "failed to charge card",
+			value:   errors.New("card declined"),
+			want:    "card declined",
+		},
+		{
+			name:    "enabled",
+			enable:  true,
+			message: "failed to charge card",
+			value:   errors.New("card declined"),
+			want:    "failed to charge card: card declined",
+		},
+		{
+			name:    "enabled with stack trace",
+			enable:  true,
+			message: "failed to charge card",
+			value:   mockStackTraceError{},
+			want:    "failed to charge card: stack",
+		},
+		{
+			name:    "enabled, empty message",
+			enable:  true,
+			message: "",
+			value:   errors.New("card declined"),
+			want:    "card declined",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			logger := slog.New(NewHandler(&buf, WithPreserveMessage(tt.enable)))
+			logger.Error(tt.message, "error", tt.value)
+
+			var got expectSchema
+			if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+				t.Fatalf("Failed to decode log output: %v", err)
+			}
+			if got.Message != tt.want {
+				t.Errorf("message = %q, want %q", got.Message, tt.want)
+			}
+		})
+	}
+}
+
+func TestHandler_joinedErrors(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(NewHandler(&buf))
+	logger.Error("error message", "error", errors.Join(errors.New("first"), mockStackTraceError{}, nil))
+
+	var got expectSchema
+	if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+		t.Fatalf("Failed to decode log output: %v", err)
+	}
+	if got.Message != "stack" {
+		t.Errorf("message = %q, want %q", got.Message, "stack")
+	}
+	want := []any{
+		map[string]any{"message": "first"},
+		map[string]any{"message": "mockStackTraceError", "stackTrace": "stack"},
+	}
+	if !reflect.DeepEqual(got.Error, want) {
+		t.Errorf("error = %v, want %v", got.Error, want)
+	}
+}
+
+func TestHandler_recordLocation(t *testing.T) {
+	tests := []struct {
+		name         string
+		options      []Option
+		value        any
+		wantLocation bool
+		wantMock     bool
+	}{
+		{
+			name:    "disabled",
+			options: []Option{WithRecordLocation(false)},
+			value:   errors.New("oops"),
+		},
+		{
+			name:         "plain error",
+			options:      []Option{WithRecordLocation(true)},
+			value:        errors.New("oops"),
+			wantLocation: true,
+		},
+		{
+			name:         "string",
+			options:      []Option{WithRecordLocation(true)},
+			value:        "oops",
+			wantLocation: true,
+		},
+		{
+			name:     "ReportLocationError",
+			options:  []Option{WithRecordLocation(true)},
+			value:    mockReportLocationError{},
+			wantMock: true,
+		},
+		{
+			name:    "StackTraceError",
+			options: []Option{WithRecordLocation(true)},
+			value:   mockStackTraceError{},
+		},
+		{
+			name: "AddSource",
+			options: []Option{
+				WithRecordLocation(true),
+				WithHandlerOptions(&slog.HandlerOptions{AddSource: true}),
+			},
+			value: errors.New("oops"),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			logger := slog.New(NewHandler(&buf, tt.options...))
+			logger.Error("error message", "error", tt.value)
+			_, file, wantLine, _ := runtime.Caller(0)
+			wantLine--
+
+			var got expectSchema
+			if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+				t.Fatalf("Failed to decode log output: %v", err)
+			}
+			var want ReportLocation
+			switch {
+			case tt.wantMock:
+				want = mockReportLocation
+			case tt.wantLocation:
+				want = ReportLocation{
+					FilePath:     file,
+					LineNumber:   wantLine,
+					FunctionName: "github.com/muhlemmer/sloggcp.TestHandler_recordLocation.func1",
+				}
+			}
+			if got.ReportLocation != want {
+				t.Errorf("reportLocation = %+v, want %+v", got.ReportLocation, want)
+			}
+		})
+	}
+}
+
+func TestHandler_errorReportMinLevel(t *testing.T) {
+	tests := []struct {
+		name     string
+		options  []Option
+		level    slog.Level
+		wantType string
+		wantMsg  string
+	}{
+		{
+			name:     "default, warning",
+			level:    slog.LevelWarn,
+			wantType: ErrorReportTypeValue,
+			wantMsg:  "card declined",
+		},
+		{
+			name:     "min error, warning",
+			options:  []Option{WithErrorReportMinLevel(slog.LevelError)},
+			level:    slog.LevelWarn,
+			wantType: "",
+			wantMsg:  "failed to charge card",
+		},
+		{
+			name:     "min error, error",
+			options:  []Option{WithErrorReportMinLevel(slog.LevelError)},
+			level:    slog.LevelError,
+			wantType: ErrorReportTypeValue,
+			wantMsg:  "card declined",
+		},
+		{
+			name:     "min error, critical",
+			options:  []Option{WithErrorReportMinLevel(slog.LevelError)},
+			level:    LevelCritical,
+			wantType: ErrorReportTypeValue,
+			wantMsg:  "card declined",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			logger := slog.New(NewHandler(&buf, tt.options...))
+			logger.Log(context.Background(), tt.level, "failed to charge card", "error", errors.New("card declined"))
+
+			var got expectSchema
+			if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+				t.Fatalf("Failed to decode log output: %v", err)
+			}
+			if got.Type != tt.wantType {
+				t.Errorf("@type = %q, want %q", got.Type, tt.wantType)
+			}
+			if got.Message != tt.wantMsg {
+				t.Errorf("message = %q, want %q", got.Message, tt.wantMsg)
+			}
+			if got.Error != "card declined" {
+				t.Errorf("error = %v, want %q", got.Error, "card declined")
+			}
+		})
+	}
+}
+
+func TestHandler_errorReportType(t *testing.T) {
+	const customType = "type.googleapis.com/google.devtools.clouderrorreporting.v2.ReportedErrorEvent"
+	tests := []struct {
+		name    string
+		options []Option
+		want    string
+	}{
+		{
+			name: "default",
+			want: ErrorReportTypeValue,
+		},
+		{
+			name:    "custom",
+			options: []Option{WithErrorReportType(customType)},
+			want:    customType,
+		},
+		{
+			name:    "empty",
+			options: []Option{WithErrorReportType("")},
+			want:    ErrorReportTypeValue,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			logger := slog.New(NewHandler(&buf, tt.options...))
+			logger.Error("error message", "error", errors.New("oops"))
+
+			var got expectSchema
+			if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+				t.Fatalf("Failed to decode log output: %v", err)
+			}
+			if got.Type != tt.want {
+				t.Errorf("@type = %q, want %q", got.Type, tt.want)
+			}
+		})
+	}
+}
+
+func TestHandler_errorSiblings(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(NewHandler(&buf)).With("service", "checkout")
+	logger.Error("failed to charge card",
+		"order_id", "o-123",
+		"amount", 42,
+		slog.Group("customer", "id", "c-1"),
+		"error", errors.New("card declined"),
+	)
+
+	var got map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+		t.Fatalf("Failed to decode log output: %v", err)
+	}
+	want := map[string]any{
+		ErrorReportTypeKey: ErrorReportTypeValue,
+		MessageKey:         "card declined",
+		ErrorKey:           "card declined",
+		"service":          "checkout",
+		"order_id":         "o-123",
+		"amount":           float64(42),
+		"customer":         map[string]any{"id": "c-1"},
+	}
+	for k, v := range want {
+		if !reflect.DeepEqual(got[k], v) {
+			t.Errorf("%s = %v, want %v", k, got[k], v)
+		}
+	}
+}
+
+func TestHandler_errorReportCallback(t *testing.T) {
+	var (
+		buf     bytes.Buffer
+		records []slog.Record
+	)
+	logger := slog.New(NewHandler(&buf,
+		WithErrorReportMinLevel(slog.LevelError),
+		WithErrorReportCallback(func(r slog.Record) {
+			if buf.Len() != 0 {
+				t.Error("callback called after write")
+			}
+			r.AddAttrs(slog.String("added", "by callback"))
+			records = append(records, r)
+		}),
+	))
+	logger.Info("info message")
+	logger.Warn("warn message", "error", errors.New("not reported"))
+	buf.Reset()
+	logger.Error("error message", "error", errors.New("oops"))
+
+	if len(records) != 1 {
+		t.Fatalf("callback called %d times, want 1", len(records))
+	}
+	if records[0].Message != "error message" {
+		t.Errorf("record message = %q, want %q", records[0].Message, "error message")
+	}
+	if bytes.Contains(buf.Bytes(), []byte("by callback")) {
+		t.Errorf("callback modified output: %s", buf.String())
+	}
+}
+
+func TestHandler_errorChain(t *testing.T) {
+	root := errors.New("connection refused")
+	err := fmt.Errorf("fetch user: %w", fmt.Errorf("query: %w", root))
+	tests := []struct {
+		name    string
+		options []Option
+		log     func(*slog.Logger)
+		want    map[string]any
+	}{
+		{
+			name:    "disabled",
+			log:     func(l *slog.Logger) { l.Error("test message", "error", err) },
+			options: nil,
+			want:    map[string]any{},
+		},
+		{
+			name:    "enabled",
+			options: []Option{WithErrorChain(true)},
+			log:     func(l *slog.Logger) { l.Error("test message", "error", err) },
+			want: map[string]any{
+				ErrorCausesKey: []any{"query: connection refused", "connection refused"},
+			},
+		},
+		{
+			name:    "grouped",
+			options: []Option{WithErrorChain(true)},
+			log:     func(l *slog.Logger) { l.WithGroup("g").Error("test message", "error", err) },
+			want: map[string]any{
+				"g": map[string]any{
+					ErrorKey:       err.Error(),
+					ErrorCausesKey: []any{"query: connection refused", "connection refused"},
+				},
+			},
+		},
+		{
+			name:    "causes attribute",
+			options: []Option{WithErrorChain(true)},
+			log:     func(l *slog.Logger) { l.Error("test message", "error", err, ErrorCausesKey, "user value") },
+			want: map[string]any{
+				ErrorCausesKey: "user value",
+			},
+		},
+		{
+			name:    "causes attribute WithAttrs",
+			options: []Option{WithErrorChain(true)},
+			log:     func(l *slog.Logger) { l.With(ErrorCausesKey, "user value").Error("test message", "error", err) },
+			want: map[string]any{
+				ErrorCausesKey: "user value",
+			},
+		},
+		{
+			name:    "no causes",
+			options: []Option{WithErrorChain(true)},
+			log:     func(l *slog.Logger) { l.Error("test message", "error", root) },
+			want:    map[string]any{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			tt.log(slog.New(NewHandler(&buf, tt.options...)))
+
+			var got map[string]any
+			if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+				t.Fatalf("Failed to decode log output: %v", err)
+			}
+			for _, k := range []string{TimeKey, SeverityKey, EventTimeKey, ErrorReportTypeKey, MessageKey, ErrorKey} {
+				delete(got, k)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("log output = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestErr(t *testing.T) {
+	tests := []struct {
+		name      string
+		attrs     []any
+		wantMsg   string
+		wantKey   string
+		wantValue any
+	}{
+		{
+			name:      "default key",
+			attrs:     []any{Err(errors.New("primary"))},
+			wantMsg:   "primary",
+			wantKey:   ErrorKey,
+			wantValue: "primary",
+		},
+		{
+			name:      "custom key",
+			attrs:     []any{slog.Attr{Key: "cause", Value: Err(errors.New("primary")).Value}},
+			wantMsg:   "primary",
+			wantKey:   "cause",
+			wantValue: "primary",
+		},
+		{
+			name: "precedence over error key",
+			attrs: []any{
+				slog.Attr{Key: "cause", Value: Err(mockReportLocationError{}).Value},
+				slog.String(ErrorKey, "secondary"),
+			},
+			wantMsg:   "mockReportLocationError",
+			wantKey:   "cause",
+			wantValue: "mockReportLocationError",
+		},
+		{
+			name:      "nil",
+			attrs:     []any{Err(nil), slog.String(ErrorKey, "secondary")},
+			wantMsg:   "secondary",
+			wantKey:   ErrorKey,
+			wantValue: "secondary",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			slog.New(NewHandler(&buf)).Error("error message", tt.attrs...)
+
+			var got map[string]any
+			if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+				t.Fatalf("Failed to decode log output: %v", err)
+			}
+			if got[ErrorReportTypeKey] != ErrorReportTypeValue {
+				t.Errorf("@type = %v, want %v", got[ErrorReportTypeKey], ErrorReportTypeValue)
+			}
+			if got[MessageKey] != tt.wantMsg {
+				t.Errorf("message = %v, want %v", got[MessageKey], tt.wantMsg)
+			}
+			if got[tt.wantKey] != tt.wantValue {
+				t.Errorf("%s = %v, want %v", tt.wantKey, got[tt.wantKey], tt.wantValue)
+			}
+		})
+	}
+}
+
+type nilPointerError struct{}
+
+func (*nilPointerError) Error() string {
+	panic("Error called on nil pointer")
+}
+
+func TestHandler_nilError(t *testing.T) {
+	var typedNil *nilPointerError
+	var nilInterface error
+	tests := []struct {
+		name  string
+		value any
+	}{
+		{"untyped nil", nil},
+		{"nil error interface", nilInterface},
+		{"typed nil", typedNil},
+		{"typed nil as error", error(typedNil)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			logger := slog.New(NewHandler(&buf))
+			logger.Error("error message", "error", tt.value)
+			logger.With("error", tt.value).Error("error message")
+
+			for line := range bytes.Lines(buf.Bytes()) {
+				var got map[string]any
+				if err := json.Unmarshal(line, &got); err != nil {
+					t.Fatalf("Failed to decode log output: %v", err)
+				}
+				if _, ok := got[ErrorReportTypeKey]; ok {
+					t.Errorf("nil error promoted to error report: %v", got)
+				}
+				if got[MessageKey] != "error message" {
+					t.Errorf("message = %v, want %q", got[MessageKey], "error message")
+				}
+				if v, ok := got[ErrorKey]; !ok || v != nil {
+					t.Errorf("error = %v (set %v), want null", v, ok)
+				}
+			}
+		})
+	}
+}
+
+// largeStackError provides a synthetic stack trace of about 1 MB.
+type largeStackError struct{}
+
+func (largeStackError) Error() string {
+	return "largeStackError"
+}
+
+func (largeStackError) StackTrace() []byte {
+	var buf bytes.Buffer
+	buf.WriteString("goroutine 1 [running]:\nmain.first()\n\t/app/main.go:1 +0x1\n")
+	for i := 0; buf.Len() < 1<<20; i++ {
+		fmt.Fprintf(&buf, "main.frame%d()\n\t/app/frames.go:%d +0x1\n", i, i)
+	}
+	return buf.Bytes()
+}
+
+func TestWithMaxStackSize(t *testing.T) {
+	tests := []struct {
+		name          string
+		options       []Option
+		wantSize      int
+		wantTruncated bool
+	}{
+		{
+			name:          "default",
+			wantSize:      DefaultMaxStackSize,
+			wantTruncated: true,
+		},
+		{
+			name:          "custom",
+			options:       []Option{WithMaxStackSize(1 << 10)},
+			wantSize:      1 << 10,
+			wantTruncated: true,
+		},
+		{
+			name:     "disabled",
+			options:  []Option{WithMaxStackSize(0)},
+			wantSize: len(largeStackError{}.StackTrace()),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			slog.New(NewHandler(&buf, tt.options...)).Error("error message", "error", largeStackError{})
+			var got map[string]any
+			if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+				t.Fatalf("Failed to decode log output: %v", err)
+			}
+			msg, _ := got[MessageKey].(string)
+			if len(msg) > tt.wantSize {
+				t.Errorf("message size = %d, want at most %d", len(msg), tt.wantSize)
+			}
+			if truncated := strings.HasSuffix(msg, "\n"+TruncatedMarker); truncated != tt.wantTruncated {
+				t.Errorf("message truncated = %v, want %v", truncated, tt.wantTruncated)
+			}
+			if !strings.HasPrefix(msg, "goroutine 1 [running]:\nmain.first()\n") {
+				t.Errorf("first frame not preserved: %.100q", msg)
+			}
+		})
+	}
+}
+
+func Test_truncateStack(t *testing.T) {
+	tests := []struct {
+		name string
+		msg  string
+		size int
+		want string
+	}{
+		{"fits", "a\nb\n", 4, "a\nb\n"},
+		{"disabled", "a\nb\n", 0, "a\nb\n"},
+		{"line boundary", "aaaa\nbbbb\n" + strings.Repeat("c", 20), len(TruncatedMarker) + 7, "aaaa\n" + TruncatedMarker},
+		{"single line", strings.Repeat("a", 30), len(TruncatedMarker) + 4, "aaaa" + TruncatedMarker},
+		{"rune boundary", "aaaé" + strings.Repeat("x", 20), len(TruncatedMarker) + 4, "aaa" + TruncatedMarker},
+		{"marker only", strings.Repeat("a", 30), 2, TruncatedMarker},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := truncateStack(tt.msg, tt.size); got != tt.want {
+				t.Errorf("truncateStack() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
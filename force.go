@@ -0,0 +1,62 @@
+package sloggcp
+
+import (
+	"context"
+	"log/slog"
+	"runtime"
+	"time"
+)
+
+// ForceKey is the attribute key of [Force].
+const ForceKey = "forced"
+
+// Force returns an attribute which marks a record as audit-critical,
+// so that it is never dropped by [WithSampling].
+// The handler doesn't emit the attribute; other handlers emit it as forced=true.
+//
+// [slog.Logger] checks the level before the attributes of a record exist,
+// so the attribute alone can't bypass the minimum level.
+// Use [LogForced] to log a record regardless of the level of the logger.
+func Force() slog.Attr {
+	return slog.Any(ForceKey, forceMarker{})
+}
+
+// forceMarker marks the attribute of [Force].
+type forceMarker struct{}
+
+// LogValue implements [slog.LogValuer].
+func (forceMarker) LogValue() slog.Value {
+	return slog.BoolValue(true)
+}
+
+// isForceAttr reports whether a is the attribute of [Force].
+func isForceAttr(a slog.Attr) bool {
+	_, ok := a.Value.Any().(forceMarker)
+	return ok && a.Key == ForceKey
+}
+
+// isForced reports whether r has the top-level attribute of [Force].
+func isForced(r *slog.Record) bool {
+	var forced bool
+	r.Attrs(func(a slog.Attr) bool {
+		forced = isForceAttr(a)
+		return !forced
+	})
+	return forced
+}
+
+// LogForced logs a record with the attribute of [Force], bypassing the level check of logger.
+// The record is passed to the handler of logger even if the handler is not enabled for level,
+// so that audit-critical events are never filtered.
+// The args are handled as by [slog.Logger.Log].
+func LogForced(ctx context.Context, logger *slog.Logger, level slog.Level, msg string, args ...any) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	var pcs [1]uintptr
+	runtime.Callers(2, pcs[:]) // skip [runtime.Callers, LogForced]
+	r := slog.NewRecord(time.Now(), level, msg, pcs[0])
+	r.Add(args...)
+	r.AddAttrs(Force())
+	_ = logger.Handler().Handle(ctx, r)
+}
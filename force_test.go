@@ -0,0 +1,65 @@
+package sloggcp
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"path/filepath"
+	"testing"
+)
+
+func TestLogForced(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(NewHandler(&buf,
+		WithHandlerOptions(&slog.HandlerOptions{AddSource: true}),
+		WithMinLevel(LevelWarning),
+	))
+	logger.Info("filtered message")
+	if buf.Len() != 0 {
+		t.Fatalf("unexpected output: %s", buf.Bytes())
+	}
+	LogForced(t.Context(), logger, slog.LevelInfo, "audit event", "user", "alice")
+
+	var got struct {
+		Severity string      `json:"severity"`
+		Message  string      `json:"message"`
+		User     string      `json:"user"`
+		Forced   any         `json:"forced"`
+		Source   slog.Source `json:"logging.googleapis.com/sourceLocation"`
+	}
+	if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+		t.Fatalf("Failed to decode log output: %v", err)
+	}
+	if got.Severity != InfoSeverity || got.Message != "audit event" || got.User != "alice" {
+		t.Errorf("output = %s", buf.Bytes())
+	}
+	if got.Forced != nil {
+		t.Errorf("forced = %v, want omitted", got.Forced)
+	}
+	if file := filepath.Base(got.Source.File); file != "force_test.go" {
+		t.Errorf("source file = %q, want %q", file, "force_test.go")
+	}
+}
+
+func TestForce_sampling(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(NewHandler(&buf, WithSampling(map[slog.Level]int{slog.LevelInfo: 10})))
+	for range 5 {
+		logger.Info("audit event", Force())
+	}
+	if n := bytes.Count(buf.Bytes(), []byte("\n")); n != 5 {
+		t.Errorf("got %d records, want 5", n)
+	}
+}
+
+func TestForce_otherHandler(t *testing.T) {
+	var buf bytes.Buffer
+	slog.New(slog.NewJSONHandler(&buf, nil)).Info("audit event", Force())
+	var got map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+		t.Fatalf("Failed to decode log output: %v", err)
+	}
+	if got[ForceKey] != true {
+		t.Errorf("%s = %v, want true", ForceKey, got[ForceKey])
+	}
+}
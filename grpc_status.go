@@ -0,0 +1,76 @@
+package sloggcp
+
+import (
+	"errors"
+	"reflect"
+)
+
+// rpcStatus is the JSON representation of a google.rpc.Status,
+// as returned by the GRPCStatus method of errors from the google.golang.org/grpc/status package.
+type rpcStatus struct {
+	Code    int64  `json:"code"`
+	Message string `json:"message"`
+	Details []any  `json:"details,omitempty"`
+}
+
+// grpcStatus returns the status of the first error in the chain of err
+// with a GRPCStatus method, as implemented by google.golang.org/grpc/status,
+// without depending on that module.
+// The method must return a value with the methods Code(), returning an integer type,
+// Message() string and optionally Details() []any.
+func grpcStatus(err error) (*rpcStatus, bool) {
+	for ; err != nil; err = errors.Unwrap(err) {
+		if s, ok := errorStatus(err); ok {
+			return s, true
+		}
+	}
+	return nil, false
+}
+
+func errorStatus(err error) (*rpcStatus, bool) {
+	method := reflect.ValueOf(err).MethodByName("GRPCStatus")
+	if !method.IsValid() {
+		return nil, false
+	}
+	if typ := method.Type(); typ.NumIn() != 0 || typ.NumOut() != 1 {
+		return nil, false
+	}
+	status := method.Call(nil)[0]
+	if status.Kind() == reflect.Pointer && status.IsNil() {
+		return nil, false
+	}
+	code, ok := callStatusMethod(status, "Code")
+	if !ok {
+		return nil, false
+	}
+	var s rpcStatus
+	switch code.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		s.Code = code.Int()
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		s.Code = int64(code.Uint())
+	default:
+		return nil, false
+	}
+	message, ok := callStatusMethod(status, "Message")
+	if !ok || message.Kind() != reflect.String {
+		return nil, false
+	}
+	s.Message = message.String()
+	if details, ok := callStatusMethod(status, "Details"); ok {
+		s.Details, _ = details.Interface().([]any)
+	}
+	return &s, true
+}
+
+// callStatusMethod calls the method name without arguments and a single result on v.
+func callStatusMethod(v reflect.Value, name string) (reflect.Value, bool) {
+	method := v.MethodByName(name)
+	if !method.IsValid() {
+		return reflect.Value{}, false
+	}
+	if typ := method.Type(); typ.NumIn() != 0 || typ.NumOut() != 1 {
+		return reflect.Value{}, false
+	}
+	return method.Call(nil)[0], true
+}
@@ -0,0 +1,122 @@
+package sloggcp
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"reflect"
+	"testing"
+)
+
+// fakeCode mimics codes.Code from google.golang.org/grpc/codes.
+type fakeCode uint32
+
+// fakeStatus mimics status.Status from google.golang.org/grpc/status.
+type fakeStatus struct {
+	code    fakeCode
+	message string
+	details []any
+}
+
+func (s *fakeStatus) Code() fakeCode  { return s.code }
+func (s *fakeStatus) Message() string { return s.message }
+func (s *fakeStatus) Details() []any  { return s.details }
+
+type fakeStatusError struct {
+	status *fakeStatus
+}
+
+func (e fakeStatusError) Error() string {
+	return fmt.Sprintf("rpc error: code = %d desc = %s", e.status.code, e.status.message)
+}
+
+func (e fakeStatusError) GRPCStatus() *fakeStatus {
+	return e.status
+}
+
+type fakeErrorInfo struct {
+	Reason string `json:"reason"`
+	Domain string `json:"domain"`
+}
+
+func TestHandler_grpcStatus(t *testing.T) {
+	err := fakeStatusError{&fakeStatus{
+		code:    5,
+		message: "user not found",
+		details: []any{fakeErrorInfo{Reason: "USER_NOT_FOUND", Domain: "example.com"}},
+	}}
+	tests := []struct {
+		name  string
+		value error
+	}{
+		{"status error", err},
+		{"wrapped status error", fmt.Errorf("get user: %w", err)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			slog.New(NewHandler(&buf)).Error("rpc failed", "error", tt.value)
+
+			var got expectSchema
+			if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+				t.Fatalf("Failed to decode log output: %v", err)
+			}
+			if got.Message != "user not found" {
+				t.Errorf("message = %q, want %q", got.Message, "user not found")
+			}
+			want := map[string]any{
+				"code":    float64(5),
+				"message": "user not found",
+				"details": []any{map[string]any{"reason": "USER_NOT_FOUND", "domain": "example.com"}},
+			}
+			if !reflect.DeepEqual(got.Error, want) {
+				t.Errorf("error = %v, want %v", got.Error, want)
+			}
+		})
+	}
+}
+
+type nilStatusError struct{}
+
+func (nilStatusError) Error() string           { return "nil status" }
+func (nilStatusError) GRPCStatus() *fakeStatus { return nil }
+
+type wrongStatusError struct{}
+
+func (wrongStatusError) Error() string      { return "wrong status" }
+func (wrongStatusError) GRPCStatus() string { return "status" }
+
+func Test_grpcStatus(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want *rpcStatus
+	}{
+		{
+			name: "status",
+			err:  fakeStatusError{&fakeStatus{code: 3, message: "bad"}},
+			want: &rpcStatus{Code: 3, Message: "bad"},
+		},
+		{
+			name: "nil status",
+			err:  nilStatusError{},
+		},
+		{
+			name: "wrong status type",
+			err:  wrongStatusError{},
+		},
+		{
+			name: "plain error",
+			err:  fmt.Errorf("plain"),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := grpcStatus(tt.err)
+			if ok != (tt.want != nil) || !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("grpcStatus() = %v, %v, want %v", got, ok, tt.want)
+			}
+		})
+	}
+}
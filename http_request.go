@@ -0,0 +1,131 @@
+package sloggcp
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// HTTPRequestKey is the special field for HTTP request information in GCP structured logging.
+// See https://cloud.google.com/logging/docs/reference/v2/rest/v2/LogEntry#HttpRequest.
+const HTTPRequestKey = "httpRequest"
+
+// HTTPRequest holds information about a HTTP request,
+// which is rendered by the Logs Explorer in a dedicated request view.
+// Zero values are omitted from the JSON output.
+// The JSON output follows the protobuf JSON mapping of the LogEntry HttpRequest:
+// sizes are rendered as decimal strings and the latency as a duration string, such as "0.123s".
+type HTTPRequest struct {
+	RequestMethod                  string
+	RequestURL                     string
+	RequestSize                    int64
+	Status                         int
+	ResponseSize                   int64
+	UserAgent                      string
+	RemoteIP                       string
+	ServerIP                       string
+	Referer                        string
+	Latency                        time.Duration
+	CacheLookup                    bool
+	CacheHit                       bool
+	CacheValidatedWithOriginServer bool
+	CacheFillBytes                 int64
+	Protocol                       string
+}
+
+type jsonHTTPRequest struct {
+	RequestMethod                  string `json:"requestMethod,omitempty"`
+	RequestURL                     string `json:"requestUrl,omitempty"`
+	RequestSize                    int64  `json:"requestSize,string,omitempty"`
+	Status                         int    `json:"status,omitempty"`
+	ResponseSize                   int64  `json:"responseSize,string,omitempty"`
+	UserAgent                      string `json:"userAgent,omitempty"`
+	RemoteIP                       string `json:"remoteIp,omitempty"`
+	ServerIP                       string `json:"serverIp,omitempty"`
+	Referer                        string `json:"referer,omitempty"`
+	Latency                        string `json:"latency,omitempty"`
+	CacheLookup                    bool   `json:"cacheLookup,omitempty"`
+	CacheHit                       bool   `json:"cacheHit,omitempty"`
+	CacheValidatedWithOriginServer bool   `json:"cacheValidatedWithOriginServer,omitempty"`
+	CacheFillBytes                 int64  `json:"cacheFillBytes,string,omitempty"`
+	Protocol                       string `json:"protocol,omitempty"`
+}
+
+// MarshalJSON implements [json.Marshaler].
+func (r HTTPRequest) MarshalJSON() ([]byte, error) {
+	out := jsonHTTPRequest{
+		RequestMethod:                  r.RequestMethod,
+		RequestURL:                     r.RequestURL,
+		RequestSize:                    r.RequestSize,
+		Status:                         r.Status,
+		ResponseSize:                   r.ResponseSize,
+		UserAgent:                      r.UserAgent,
+		RemoteIP:                       r.RemoteIP,
+		ServerIP:                       r.ServerIP,
+		Referer:                        r.Referer,
+		CacheLookup:                    r.CacheLookup,
+		CacheHit:                       r.CacheHit,
+		CacheValidatedWithOriginServer: r.CacheValidatedWithOriginServer,
+		CacheFillBytes:                 r.CacheFillBytes,
+		Protocol:                       r.Protocol,
+	}
+	if r.Latency != 0 {
+		out.Latency = LatencyString(r.Latency)
+	}
+	return json.Marshal(out)
+}
+
+// HTTP returns an attribute for the [HTTPRequestKey] special field.
+func HTTP(req HTTPRequest) slog.Attr {
+	return slog.Any(HTTPRequestKey, req)
+}
+
+// LatencyString formats d as a protobuf JSON Duration, such as "3.500s",
+// as expected for the latency of the [HTTPRequestKey] special field.
+// The fractional seconds are written with 0, 3, 6 or 9 digits.
+// A zero duration is formatted as "0s" and negative durations have a leading "-".
+func LatencyString(d time.Duration) string {
+	sign := ""
+	if d < 0 {
+		sign = "-"
+		d = -d
+	}
+	secs := int64(d / time.Second)
+	nanos := int64(d % time.Second)
+	s := sign + strconv.FormatInt(secs, 10)
+	switch {
+	case nanos == 0:
+	case nanos%1e6 == 0:
+		s += "." + leftPad(strconv.FormatInt(nanos/1e6, 10), 3)
+	case nanos%1e3 == 0:
+		s += "." + leftPad(strconv.FormatInt(nanos/1e3, 10), 6)
+	default:
+		s += "." + leftPad(strconv.FormatInt(nanos, 10), 9)
+	}
+	return s + "s"
+}
+
+func leftPad(s string, n int) string {
+	for len(s) < n {
+		s = "0" + s
+	}
+	return s
+}
+
+// SeverityForStatus maps a HTTP response status code to a log level:
+// 5xx statuses map to [LevelError], 401 and 403 to [LevelNotice],
+// other 4xx statuses to [LevelWarning] and all others to [LevelInfo].
+func SeverityForStatus(code int) slog.Level {
+	switch {
+	case code >= 500:
+		return LevelError
+	case code == http.StatusUnauthorized, code == http.StatusForbidden:
+		return LevelNotice
+	case code >= 400:
+		return LevelWarning
+	default:
+		return LevelInfo
+	}
+}
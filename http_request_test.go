@@ -0,0 +1,172 @@
+package sloggcp
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"reflect"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestHTTPRequest_MarshalJSON(t *testing.T) {
+	tests := []struct {
+		name string
+		req  HTTPRequest
+		want string
+	}{
+		{
+			name: "zero",
+			req:  HTTPRequest{},
+			want: `{}`,
+		},
+		{
+			name: "all fields",
+			req: HTTPRequest{
+				RequestMethod:                  "GET",
+				RequestURL:                     "https://example.com/foo",
+				RequestSize:                    100,
+				Status:                         200,
+				ResponseSize:                   2000,
+				UserAgent:                      "test-agent",
+				RemoteIP:                       "192.168.1.1",
+				ServerIP:                       "10.0.0.1",
+				Referer:                        "https://example.com",
+				Latency:                        3500 * time.Millisecond,
+				CacheLookup:                    true,
+				CacheHit:                       true,
+				CacheValidatedWithOriginServer: true,
+				CacheFillBytes:                 300,
+				Protocol:                       "HTTP/1.1",
+			},
+			want: `{"requestMethod":"GET","requestUrl":"https://example.com/foo","requestSize":"100","status":200,"responseSize":"2000","userAgent":"test-agent","remoteIp":"192.168.1.1","serverIp":"10.0.0.1","referer":"https://example.com","latency":"3.500s","cacheLookup":true,"cacheHit":true,"cacheValidatedWithOriginServer":true,"cacheFillBytes":"300","protocol":"HTTP/1.1"}`,
+		},
+		{
+			// https://cloud.google.com/logging/docs/reference/v2/rest/v2/LogEntry#HttpRequest
+			name: "golden",
+			req: HTTPRequest{
+				RequestMethod: "POST",
+				RequestURL:    "https://example.com/api/v1/items?q=1",
+				RequestSize:   1234,
+				Status:        201,
+				ResponseSize:  5678,
+				UserAgent:     "Mozilla/5.0 (X11; Linux x86_64)",
+				RemoteIP:      "203.0.113.7",
+				Referer:       "https://example.com/",
+				Latency:       123 * time.Millisecond,
+				CacheLookup:   true,
+				Protocol:      "HTTP/2",
+			},
+			want: `{"requestMethod":"POST","requestUrl":"https://example.com/api/v1/items?q=1","requestSize":"1234","status":201,"responseSize":"5678","userAgent":"Mozilla/5.0 (X11; Linux x86_64)","remoteIp":"203.0.113.7","referer":"https://example.com/","latency":"0.123s","cacheLookup":true,"protocol":"HTTP/2"}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := json.Marshal(tt.req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("MarshalJSON() =\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLatencyString(t *testing.T) {
+	tests := []struct {
+		d    time.Duration
+		want string
+	}{
+		{0, "0s"},
+		{time.Second, "1s"},
+		{3500 * time.Millisecond, "3.500s"},
+		{1500 * time.Microsecond, "0.001500s"},
+		{time.Nanosecond, "0.000000001s"},
+		{12345678 * time.Microsecond, "12.345678s"},
+		{12*time.Second + 345678901*time.Nanosecond, "12.345678901s"},
+		{-1500 * time.Millisecond, "-1.500s"},
+		{-time.Nanosecond, "-0.000000001s"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.want, func(t *testing.T) {
+			if got := LatencyString(tt.d); got != tt.want {
+				t.Errorf("LatencyString() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHTTP(t *testing.T) {
+	req := HTTPRequest{
+		RequestMethod: "POST",
+		Status:        201,
+		Latency:       time.Second,
+	}
+	want := map[string]any{
+		"latency":       "1s",
+		"requestMethod": "POST",
+		"status":        float64(201),
+	}
+
+	tests := []struct {
+		name    string
+		handler func(*bytes.Buffer) slog.Handler
+	}{
+		{
+			name: "error reporting handler",
+			handler: func(buf *bytes.Buffer) slog.Handler {
+				return NewErrorReportingHandler(buf, nil)
+			},
+		},
+		{
+			name: "ReplaceAttr",
+			handler: func(buf *bytes.Buffer) slog.Handler {
+				return slog.NewJSONHandler(buf, &slog.HandlerOptions{ReplaceAttr: ReplaceAttr})
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			slog.New(tt.handler(&buf)).Info("request", HTTP(req))
+
+			var got map[string]any
+			if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+				t.Fatalf("Failed to decode log output: %v", err)
+			}
+			if !reflect.DeepEqual(got[HTTPRequestKey], want) {
+				t.Errorf("%s = %v, want %v", HTTPRequestKey, got[HTTPRequestKey], want)
+			}
+		})
+	}
+}
+
+func TestSeverityForStatus(t *testing.T) {
+	tests := []struct {
+		code int
+		want slog.Level
+	}{
+		{http.StatusContinue, LevelInfo},
+		{http.StatusOK, LevelInfo},
+		{http.StatusNoContent, LevelInfo},
+		{http.StatusFound, LevelInfo},
+		{http.StatusNotModified, LevelInfo},
+		{http.StatusBadRequest, LevelWarning},
+		{http.StatusUnauthorized, LevelNotice},
+		{http.StatusForbidden, LevelNotice},
+		{http.StatusNotFound, LevelWarning},
+		{http.StatusTooManyRequests, LevelWarning},
+		{http.StatusInternalServerError, LevelError},
+		{http.StatusServiceUnavailable, LevelError},
+	}
+	for _, tt := range tests {
+		t.Run(strconv.Itoa(tt.code), func(t *testing.T) {
+			if got := SeverityForStatus(tt.code); got != tt.want {
+				t.Errorf("SeverityForStatus(%d) = %v, want %v", tt.code, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,46 @@
+package sloggcp
+
+import (
+	"hash/fnv"
+	"log/slog"
+	"os"
+	"strconv"
+	"sync/atomic"
+)
+
+// InsertIDKey is the special field for the unique identifier of a log entry.
+// Cloud Logging removes entries with the same insertId and timestamp as duplicates.
+// See https://cloud.google.com/logging/docs/structured-logging#special-payload-fields.
+const InsertIDKey = "logging.googleapis.com/insertId"
+
+// InsertID returns an attribute for the [InsertIDKey] special field.
+// The handler always emits it at the top level, even when logged inside a group.
+func InsertID(id string) slog.Attr {
+	return slog.String(InsertIDKey, id)
+}
+
+// checkAndSetInsertID sets the [InsertIDKey] field in out,
+// at any group depth. It returns false if a is not an insertId attribute.
+func checkAndSetInsertID(a slog.Attr, out map[string]any) bool {
+	if a.Key != InsertIDKey {
+		return false
+	}
+	out[InsertIDKey] = a.Value.Resolve().String()
+	return true
+}
+
+var insertIDCounter atomic.Uint64
+
+// newInsertID generates an insertId for r, from the record time and message,
+// the process ID and a process wide counter.
+// The counter makes each ID unique, even for identical records.
+func newInsertID(r *slog.Record) string {
+	h := fnv.New64a()
+	var b []byte
+	b = strconv.AppendInt(b, r.Time.UnixNano(), 10)
+	b = append(b, r.Message...)
+	b = strconv.AppendInt(b, int64(os.Getpid()), 10)
+	b = strconv.AppendUint(b, insertIDCounter.Add(1), 10)
+	h.Write(b)
+	return strconv.FormatUint(h.Sum64(), 16)
+}
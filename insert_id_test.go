@@ -0,0 +1,91 @@
+package sloggcp
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"testing"
+)
+
+func TestInsertID(t *testing.T) {
+	tests := []struct {
+		name string
+		log  func(*slog.Logger)
+	}{
+		{
+			name: "top-level",
+			log: func(l *slog.Logger) {
+				l.Info("test message", InsertID("id-1"))
+			},
+		},
+		{
+			name: "record group",
+			log: func(l *slog.Logger) {
+				l.Info("test message", slog.Group("group", InsertID("id-1")))
+			},
+		},
+		{
+			name: "WithGroup",
+			log: func(l *slog.Logger) {
+				l.WithGroup("group").Info("test message", InsertID("id-1"))
+			},
+		},
+		{
+			name: "WithAttrs",
+			log: func(l *slog.Logger) {
+				l.WithGroup("group").With(InsertID("id-1")).Info("test message")
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			tt.log(slog.New(NewHandler(&buf, WithAutoInsertID())))
+
+			var got map[string]any
+			if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+				t.Fatalf("Failed to decode log output: %v", err)
+			}
+			if got[InsertIDKey] != "id-1" {
+				t.Errorf("%s = %v, want %q", InsertIDKey, got[InsertIDKey], "id-1")
+			}
+			if group, ok := got["group"].(map[string]any); ok {
+				if _, ok := group[InsertIDKey]; ok {
+					t.Errorf("%s emitted inside group: %v", InsertIDKey, group)
+				}
+			}
+		})
+	}
+}
+
+func TestWithAutoInsertID(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(NewHandler(&buf, WithAutoInsertID()))
+	ids := make(map[string]bool)
+	for range 3 {
+		buf.Reset()
+		logger.Info("test message")
+		var got map[string]any
+		if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+			t.Fatalf("Failed to decode log output: %v", err)
+		}
+		id, ok := got[InsertIDKey].(string)
+		if !ok || id == "" {
+			t.Fatalf("%s = %v, want generated ID", InsertIDKey, got[InsertIDKey])
+		}
+		if ids[id] {
+			t.Errorf("duplicate %s %q", InsertIDKey, id)
+		}
+		ids[id] = true
+	}
+
+	buf.Reset()
+	slog.New(NewHandler(&buf)).Info("test message")
+	var got map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+		t.Fatalf("Failed to decode log output: %v", err)
+	}
+	if _, ok := got[InsertIDKey]; ok {
+		t.Errorf("%s set without WithAutoInsertID", InsertIDKey)
+	}
+}
@@ -0,0 +1,158 @@
+package sloggcp
+
+import (
+	"encoding/json"
+	"log/slog"
+	"maps"
+)
+
+// LabelsKey is the special field for user defined labels in GCP structured logging.
+// Labels are indexed, string only key/value pairs.
+// See https://cloud.google.com/logging/docs/structured-logging#special-payload-fields.
+const LabelsKey = "logging.googleapis.com/labels"
+
+// Labels returns an attribute for the [LabelsKey] special field,
+// from alternating key and value pairs.
+// A trailing key without value is ignored.
+// Use [LabelsAny] for values which are no strings.
+//
+// The handler merges the labels of all [LabelsKey] attributes,
+// including those added with [slog.Logger.With], into a single top-level labels object.
+// Labels are global to the log entry, so groups are ignored.
+// A label set by a later attribute overrides a label with the same key.
+func Labels(pairs ...string) slog.Attr {
+	attrs := make([]slog.Attr, 0, len(pairs)/2)
+	for i := 0; i+1 < len(pairs); i += 2 {
+		attrs = append(attrs, slog.String(pairs[i], pairs[i+1]))
+	}
+	return slog.Attr{Key: LabelsKey, Value: slog.GroupValue(attrs...)}
+}
+
+// BadLabelKey is the label key of [LabelsAny] for a key which is no string,
+// or a trailing key without value, the same as the "!BADKEY" of [slog.Logger.Log].
+const BadLabelKey = "!BADKEY"
+
+// LabelsAny returns an attribute for the [LabelsKey] special field, like [Labels],
+// from alternating key and value pairs with values of any type.
+// As with [slog.Logger.Log], a key which is no string, or a trailing key without value,
+// is set as the value of a [BadLabelKey] label, and the pairs continue with the next argument.
+//
+// Labels are string only, so the values are coerced to strings by the handler and by [NewReplaceAttr]:
+// numbers, booleans and other scalars use their string form, such as "3" and "true",
+// and groups, maps, slices and structs are rendered as a JSON string.
+// The same coercion applies to [LabelsKey] groups which are not created by LabelsAny.
+// A ReplaceAttr function is not called for groups, so [NewReplaceAttr] doesn't coerce group values.
+// Coerced values are reported to the function of [WithLabelWarning].
+func LabelsAny(pairs ...any) slog.Attr {
+	attrs := make([]slog.Attr, 0, len(pairs)/2)
+	for i := 0; i < len(pairs); {
+		key, ok := pairs[i].(string)
+		if !ok || i+1 == len(pairs) {
+			attrs = append(attrs, slog.Any(BadLabelKey, pairs[i]))
+			i++
+			continue
+		}
+		attrs = append(attrs, slog.Any(key, pairs[i+1]))
+		i += 2
+	}
+	return slog.Attr{Key: LabelsKey, Value: slog.GroupValue(attrs...)}
+}
+
+// WithLabelWarning sets fn to be called for each label value which is no string
+// and is coerced to a string, with the key and the original value of the label.
+// This allows detecting labels which don't pass the intended value, such as nested groups.
+// fn may be called concurrently. By default, values are coerced without warning.
+// The option applies to the handler and to [NewReplaceAttr].
+func WithLabelWarning(fn func(key string, value slog.Value)) Option {
+	return func(c *config) {
+		c.labelWarning = fn
+	}
+}
+
+// checkAndSetLabels merges the labels from a into the labels object in out,
+// at any group depth.
+// It returns false if a is not a [LabelsKey] group attribute.
+func (c *config) checkAndSetLabels(a slog.Attr, out map[string]any) bool {
+	if a.Key != LabelsKey {
+		return false
+	}
+	v := a.Value.Resolve()
+	if v.Kind() != slog.KindGroup {
+		return false
+	}
+	labels, ok := out[LabelsKey].(map[string]string)
+	if !ok {
+		labels = make(map[string]string, len(v.Group()))
+		out[LabelsKey] = labels
+	}
+	for _, label := range v.Group() {
+		labels[label.Key] = c.labelValue(label)
+	}
+	return true
+}
+
+// labelValue returns the value of the label a as a string,
+// which is reported to the function of [WithLabelWarning] if it is coerced.
+func (c *config) labelValue(a slog.Attr) string {
+	v := a.Value.Resolve()
+	if v.Kind() == slog.KindString {
+		return v.String()
+	}
+	if c.labelWarning != nil {
+		c.labelWarning(a.Key, v)
+	}
+	return labelValue(v)
+}
+
+// labelValue coerces v into a string.
+// Scalars use their string form. Group values and other values
+// which don't extract to a string are rendered as a JSON string.
+func labelValue(v slog.Value) string {
+	v = v.Resolve()
+	switch v.Kind() {
+	case slog.KindGroup, slog.KindAny:
+		value := extractValue(v)
+		switch value := value.(type) {
+		case nil:
+			return ""
+		case string:
+			return value
+		}
+		b, err := json.Marshal(value)
+		if err != nil {
+			return err.Error()
+		}
+		return string(b)
+	}
+	return v.String()
+}
+
+// ComponentLabel is the label set by [WithComponent].
+const ComponentLabel = "component"
+
+// WithComponent adds the [ComponentLabel] label with name to every record,
+// so that records of a subsystem can be filtered in Cloud Logging.
+// Like all labels, it is set at the top level, regardless of groups.
+// Labels set by attributes override the component label.
+func WithComponent(name string) Option {
+	return func(c *config) {
+		c.component = name
+	}
+}
+
+// setConfigLabels sets the labels of [WithResourceLabels], [WithComponent] and [WithProcessLabels] in out.
+// It must be called before the attributes are handled, so that they can override the labels.
+func (c *config) setConfigLabels(out map[string]any) {
+	if c.component == "" && !c.processLabels && len(c.resourceLabels) == 0 {
+		return
+	}
+	labels := make(map[string]string, len(c.resourceLabels)+3)
+	maps.Copy(labels, c.resourceLabels)
+	if c.component != "" {
+		labels[ComponentLabel] = c.component
+	}
+	if c.processLabels {
+		setProcessLabels(labels)
+	}
+	out[LabelsKey] = labels
+}
@@ -0,0 +1,270 @@
+package sloggcp
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"reflect"
+	"sync"
+	"testing"
+)
+
+func TestLabels(t *testing.T) {
+	tests := []struct {
+		name  string
+		pairs []string
+		want  slog.Attr
+	}{
+		{
+			name:  "empty",
+			pairs: nil,
+			want:  slog.Attr{Key: LabelsKey, Value: slog.GroupValue()},
+		},
+		{
+			name:  "pairs",
+			pairs: []string{"foo", "bar", "hello", "world"},
+			want: slog.Attr{Key: LabelsKey, Value: slog.GroupValue(
+				slog.String("foo", "bar"),
+				slog.String("hello", "world"),
+			)},
+		},
+		{
+			name:  "trailing key",
+			pairs: []string{"foo", "bar", "hello"},
+			want: slog.Attr{Key: LabelsKey, Value: slog.GroupValue(
+				slog.String("foo", "bar"),
+			)},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Labels(tt.pairs...); !got.Equal(tt.want) {
+				t.Errorf("Labels() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLabelsAny(t *testing.T) {
+	tests := []struct {
+		name  string
+		pairs []any
+		want  map[string]any
+	}{
+		{
+			name:  "empty",
+			pairs: nil,
+			want:  map[string]any{},
+		},
+		{
+			name:  "coerced values",
+			pairs: []any{"foo", "bar", "retries", 3, "cached", true, "ratio", 0.5, "nested", map[string]any{"a": 1}, "list", []int{1, 2}},
+			want: map[string]any{
+				"foo":     "bar",
+				"retries": "3",
+				"cached":  "true",
+				"ratio":   "0.5",
+				"nested":  `{"a":1}`,
+				"list":    "[1,2]",
+			},
+		},
+		{
+			name:  "group value",
+			pairs: []any{"group", slog.GroupValue(slog.Int("a", 1))},
+			want:  map[string]any{"group": `{"a":1}`},
+		},
+		{
+			name:  "key which is no string",
+			pairs: []any{7, "foo", "bar"},
+			want:  map[string]any{BadLabelKey: "7", "foo": "bar"},
+		},
+		{
+			name:  "trailing key",
+			pairs: []any{"foo", "bar", "hello"},
+			want:  map[string]any{"foo": "bar", BadLabelKey: "hello"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			slog.New(NewHandler(&buf)).Info("test", LabelsAny(tt.pairs...))
+
+			var got map[string]any
+			if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+				t.Fatalf("Failed to decode log output: %v", err)
+			}
+			labels, _ := got[LabelsKey].(map[string]any)
+			if len(tt.want) == 0 && len(labels) == 0 {
+				return
+			}
+			if !reflect.DeepEqual(labels, tt.want) {
+				t.Errorf("labels = %v, want %v", labels, tt.want)
+			}
+		})
+	}
+}
+
+func TestWithLabelWarning(t *testing.T) {
+	var (
+		mu       sync.Mutex
+		warnings []string
+	)
+	warn := WithLabelWarning(func(key string, value slog.Value) {
+		mu.Lock()
+		defer mu.Unlock()
+		warnings = append(warnings, key+"="+value.String())
+	})
+
+	var buf bytes.Buffer
+	slog.New(NewHandler(&buf, warn)).Info("test", LabelsAny("env", "prod", "retries", 3), Labels("region", "eu"))
+	if want := []string{"retries=3"}; !reflect.DeepEqual(warnings, want) {
+		t.Errorf("handler warnings = %v, want %v", warnings, want)
+	}
+
+	warnings = nil
+	buf.Reset()
+	slog.New(slog.NewJSONHandler(&buf, &slog.HandlerOptions{ReplaceAttr: NewReplaceAttr(warn)})).
+		Info("test", LabelsAny("env", "prod", "retries", 3, "cached"))
+	if want := []string{"retries=3"}; !reflect.DeepEqual(warnings, want) {
+		t.Errorf("ReplaceAttr warnings = %v, want %v", warnings, want)
+	}
+	var got map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+		t.Fatalf("Failed to decode log output: %v", err)
+	}
+	want := map[string]any{"env": "prod", "retries": "3", BadLabelKey: "cached"}
+	if !reflect.DeepEqual(got[LabelsKey], want) {
+		t.Errorf("ReplaceAttr labels = %v, want %v", got[LabelsKey], want)
+	}
+}
+
+func TestHandler_labels(t *testing.T) {
+	tests := []struct {
+		name string
+		log  func(logger *slog.Logger)
+		want map[string]any
+	}{
+		{
+			name: "no labels",
+			log: func(logger *slog.Logger) {
+				logger.Info("test")
+			},
+			want: nil,
+		},
+		{
+			name: "record labels",
+			log: func(logger *slog.Logger) {
+				logger.Info("test", Labels("foo", "bar"))
+			},
+			want: map[string]any{"foo": "bar"},
+		},
+		{
+			name: "merged with WithAttrs",
+			log: func(logger *slog.Logger) {
+				logger = logger.With(Labels("foo", "bar", "region", "us"))
+				logger = logger.With(Labels("service", "api"))
+				logger.Info("test", Labels("region", "eu"))
+			},
+			want: map[string]any{
+				"foo":     "bar",
+				"region":  "eu",
+				"service": "api",
+			},
+		},
+		{
+			name: "nested groups",
+			log: func(logger *slog.Logger) {
+				logger = logger.With(Labels("foo", "bar"))
+				logger = logger.WithGroup("svc").With(Labels("region", "eu"))
+				logger = logger.WithGroup("handler").With(Labels("service", "api"))
+				logger.Info("test", Labels("method", "GET"), slog.Group("inline", Labels("inline", "true")))
+			},
+			want: map[string]any{
+				"foo":     "bar",
+				"region":  "eu",
+				"service": "api",
+				"method":  "GET",
+				"inline":  "true",
+			},
+		},
+		{
+			name: "non-string values",
+			log: func(logger *slog.Logger) {
+				logger.Info("test", slog.Group(LabelsKey,
+					slog.Int("int", 3),
+					slog.Bool("bool", true),
+					slog.Group("nested", slog.String("foo", "bar")),
+				))
+			},
+			want: map[string]any{
+				"int":    "3",
+				"bool":   "true",
+				"nested": `{"foo":"bar"}`,
+			},
+		},
+		{
+			name: "coerced by LabelsAny",
+			log: func(logger *slog.Logger) {
+				logger.Info("test", LabelsAny("retries", 3, "cached", false, "list", []string{"a"}))
+			},
+			want: map[string]any{
+				"retries": "3",
+				"cached":  "false",
+				"list":    `["a"]`,
+			},
+		},
+		{
+			name: "non-string any values",
+			log: func(logger *slog.Logger) {
+				logger.Info("test", slog.Group(LabelsKey, slog.Any("map", map[string]int{"a": 1})))
+			},
+			want: map[string]any{
+				"map": `{"a":1}`,
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			tt.log(slog.New(NewErrorReportingHandler(&buf, nil)))
+
+			var got map[string]any
+			if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+				t.Fatalf("Failed to decode log output: %v", err)
+			}
+			labels, _ := got[LabelsKey].(map[string]any)
+			if !reflect.DeepEqual(labels, tt.want) {
+				t.Errorf("%s = %v, want %v", LabelsKey, got[LabelsKey], tt.want)
+			}
+		})
+	}
+}
+
+func TestWithComponent(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(NewHandler(&buf, WithComponent("billing")))
+	logger.Info("test message")
+	logger.WithGroup("group").With("foo", "bar").Warn("test message")
+	logger.Error("test message", Labels("tenant", "acme"), "error", "oops")
+	logger.Info("test message", Labels(ComponentLabel, "override"))
+
+	want := []map[string]string{
+		{ComponentLabel: "billing"},
+		{ComponentLabel: "billing"},
+		{ComponentLabel: "billing", "tenant": "acme"},
+		{ComponentLabel: "override"},
+	}
+	var got []map[string]string
+	for line := range bytes.Lines(buf.Bytes()) {
+		var record struct {
+			Labels map[string]string `json:"logging.googleapis.com/labels"`
+		}
+		if err := json.Unmarshal(line, &record); err != nil {
+			t.Fatalf("Failed to decode log output: %v", err)
+		}
+		got = append(got, record.Labels)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("labels = %v, want %v", got, want)
+	}
+}
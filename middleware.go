@@ -0,0 +1,156 @@
+package sloggcp
+
+import (
+	"bufio"
+	"log/slog"
+	"net"
+	"net/http"
+	"time"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+// MiddlewareOption configures the middleware returned by [LoggingMiddleware].
+type MiddlewareOption func(*middleware)
+
+// WithStatusLevel overrides the mapping of HTTP response status codes to log levels,
+// used by [LoggingMiddleware].
+func WithStatusLevel(fn func(status int) slog.Level) MiddlewareOption {
+	return func(m *middleware) {
+		m.statusLevel = fn
+	}
+}
+
+type middleware struct {
+	logger      *slog.Logger
+	statusLevel func(status int) slog.Level
+	next        http.Handler
+}
+
+// LoggingMiddleware returns a HTTP middleware which logs a single record for each request,
+// with the [HTTPRequestKey] special field populated from the request and response.
+//
+// By default, the level of each record is determined by [SeverityForStatus].
+// Use [WithStatusLevel] to change this mapping.
+//
+// When the request carries a [CloudTraceHeader] and its context does not already hold a
+// valid OpenTelemetry span context, the trace values are added to the request context
+// using [ContextWithTrace]. The wrapped handler receives the same context.
+//
+// The [http.ResponseWriter] passed to the wrapped handler forwards [http.Flusher] and [http.Hijacker]
+// to the original writer and unwraps for [http.ResponseController], for streaming responses.
+func LoggingMiddleware(logger *slog.Logger, options ...MiddlewareOption) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		m := &middleware{
+			logger:      logger,
+			statusLevel: SeverityForStatus,
+			next:        next,
+		}
+		for _, option := range options {
+			option(m)
+		}
+		return m
+	}
+}
+
+// ServeHTTP implements [http.Handler].
+func (m *middleware) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+	ctx := r.Context()
+	if !trace.SpanContextFromContext(ctx).IsValid() {
+		if traceID, spanID, sampled, ok := ParseCloudTraceHeader(r.Header.Get(CloudTraceHeader)); ok {
+			ctx = ContextWithTrace(ctx, traceID, spanID, sampled)
+			r = r.WithContext(ctx)
+		}
+	}
+
+	rec := &responseRecorder{ResponseWriter: w}
+	m.next.ServeHTTP(rec, r)
+	if rec.status == 0 {
+		rec.status = http.StatusOK
+	}
+
+	req := HTTPRequest{
+		RequestMethod: r.Method,
+		RequestURL:    r.URL.String(),
+		Status:        rec.status,
+		ResponseSize:  rec.size,
+		UserAgent:     r.UserAgent(),
+		RemoteIP:      remoteIP(r.RemoteAddr),
+		Referer:       r.Referer(),
+		Latency:       time.Since(start),
+		Protocol:      r.Proto,
+	}
+	if r.ContentLength > 0 {
+		req.RequestSize = r.ContentLength
+	}
+	m.logger.LogAttrs(ctx, m.statusLevel(rec.status), "http request", HTTP(req))
+}
+
+func remoteIP(addr string) string {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return addr
+	}
+	return host
+}
+
+// responseRecorder captures the status code and amount of bytes written.
+type responseRecorder struct {
+	http.ResponseWriter
+	status int
+	size   int64
+}
+
+// WriteHeader implements [http.ResponseWriter].
+// Only the first non-informational status code is recorded and passed on,
+// consistent with the standard library ignoring superfluous calls.
+func (r *responseRecorder) WriteHeader(code int) {
+	if r.status != 0 {
+		return
+	}
+	if code >= 100 && code < 200 && code != http.StatusSwitchingProtocols {
+		r.ResponseWriter.WriteHeader(code)
+		return
+	}
+	r.status = code
+	r.ResponseWriter.WriteHeader(code)
+}
+
+// Write implements [http.ResponseWriter].
+func (r *responseRecorder) Write(b []byte) (int, error) {
+	if r.status == 0 {
+		r.status = http.StatusOK
+	}
+	n, err := r.ResponseWriter.Write(b)
+	r.size += int64(n)
+	return n, err
+}
+
+// Flush implements [http.Flusher], for streaming responses such as server-sent events.
+// It flushes the underlying [http.ResponseWriter], if it supports it.
+func (r *responseRecorder) Flush() {
+	if r.status == 0 {
+		r.status = http.StatusOK
+	}
+	_ = http.NewResponseController(r.ResponseWriter).Flush()
+}
+
+// Hijack implements [http.Hijacker], for protocols such as WebSocket.
+// It hijacks the connection of the underlying [http.ResponseWriter],
+// or returns an error if it doesn't support it.
+// The status of a hijacked connection is recorded as [http.StatusSwitchingProtocols],
+// unless a status was written before.
+func (r *responseRecorder) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	conn, rw, err := http.NewResponseController(r.ResponseWriter).Hijack()
+	if err == nil && r.status == 0 {
+		r.status = http.StatusSwitchingProtocols
+	}
+	return conn, rw, err
+}
+
+// Unwrap returns the underlying [http.ResponseWriter],
+// for use by [http.ResponseController].
+func (r *responseRecorder) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
@@ -0,0 +1,214 @@
+package sloggcp
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestLoggingMiddleware(t *testing.T) {
+	tests := []struct {
+		name         string
+		options      []MiddlewareOption
+		handler      http.HandlerFunc
+		header       http.Header
+		wantStatus   float64
+		wantSeverity string
+		wantTrace    string
+	}{
+		{
+			name: "no WriteHeader",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				io.WriteString(w, "hello")
+			},
+			wantStatus:   200,
+			wantSeverity: InfoSeverity,
+		},
+		{
+			name:         "nothing written",
+			handler:      func(w http.ResponseWriter, r *http.Request) {},
+			wantStatus:   200,
+			wantSeverity: InfoSeverity,
+		},
+		{
+			name: "multiple WriteHeader",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusNotFound)
+				w.WriteHeader(http.StatusInternalServerError)
+				io.WriteString(w, "not")
+				io.WriteString(w, " found")
+			},
+			wantStatus:   404,
+			wantSeverity: WarningSeverity,
+		},
+		{
+			name: "informational header",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusEarlyHints)
+				w.WriteHeader(http.StatusServiceUnavailable)
+			},
+			wantStatus:   503,
+			wantSeverity: ErrorSeverity,
+		},
+		{
+			name: "custom status level",
+			options: []MiddlewareOption{
+				WithStatusLevel(func(int) slog.Level { return LevelNotice }),
+			},
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusInternalServerError)
+			},
+			wantStatus:   500,
+			wantSeverity: NoticeSeverity,
+		},
+		{
+			name: "trace header",
+			header: http.Header{
+				CloudTraceHeader: []string{"4bf92f3577b34da6a3ce929d0e0e4736/1;o=1"},
+			},
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				slog.New(NewErrorReportingHandler(io.Discard, nil)).InfoContext(r.Context(), "inner")
+			},
+			wantStatus:   200,
+			wantSeverity: InfoSeverity,
+			wantTrace:    "4bf92f3577b34da6a3ce929d0e0e4736",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			logger := slog.New(NewErrorReportingHandler(&buf, nil))
+			h := LoggingMiddleware(logger, tt.options...)(tt.handler)
+
+			req := httptest.NewRequest(http.MethodGet, "/foo?bar=baz", nil)
+			for k, v := range tt.header {
+				req.Header[k] = v
+			}
+			req.Header.Set("User-Agent", "test-agent")
+			resp := httptest.NewRecorder()
+			h.ServeHTTP(resp, req)
+
+			var got struct {
+				Severity    string         `json:"severity"`
+				Trace       string         `json:"logging.googleapis.com/trace"`
+				HTTPRequest map[string]any `json:"httpRequest"`
+			}
+			if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+				t.Fatalf("Failed to decode log output: %v", err)
+			}
+			if got.Severity != tt.wantSeverity {
+				t.Errorf("severity = %v, want %v", got.Severity, tt.wantSeverity)
+			}
+			if got.Trace != tt.wantTrace {
+				t.Errorf("trace = %v, want %v", got.Trace, tt.wantTrace)
+			}
+			wantRequest := map[string]any{
+				"requestMethod": "GET",
+				"requestUrl":    "/foo?bar=baz",
+				"status":        tt.wantStatus,
+				"userAgent":     "test-agent",
+				"remoteIp":      "192.0.2.1",
+				"protocol":      "HTTP/1.1",
+			}
+			if n := resp.Body.Len(); n > 0 {
+				wantRequest["responseSize"] = strconv.Itoa(n)
+			}
+			for k, v := range wantRequest {
+				if got.HTTPRequest[k] != v {
+					t.Errorf("httpRequest.%s = %v, want %v", k, got.HTTPRequest[k], v)
+				}
+			}
+			latency, _ := got.HTTPRequest["latency"].(string)
+			if !strings.HasSuffix(latency, "s") {
+				t.Errorf("httpRequest.latency = %q, want duration string", latency)
+			}
+		})
+	}
+}
+
+func TestLoggingMiddleware_flush(t *testing.T) {
+	var buf bytes.Buffer
+	mw := LoggingMiddleware(slog.New(NewHandler(&buf)))
+	handler := mw(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, "event: 1\n\n")
+		f, ok := w.(http.Flusher)
+		if !ok {
+			t.Fatal("ResponseWriter is no http.Flusher")
+		}
+		f.Flush()
+		io.WriteString(w, "event: 2\n\n")
+		if err := http.NewResponseController(w).Flush(); err != nil {
+			t.Errorf("ResponseController.Flush() = %v", err)
+		}
+	}))
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/events", nil))
+	if !rec.Flushed {
+		t.Error("response not flushed through the middleware")
+	}
+
+	var got struct {
+		HTTPRequest struct {
+			Status int `json:"status"`
+		} `json:"httpRequest"`
+	}
+	if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+		t.Fatalf("Failed to decode log output: %v", err)
+	}
+	if got.HTTPRequest.Status != http.StatusOK {
+		t.Errorf("status = %d, want %d", got.HTTPRequest.Status, http.StatusOK)
+	}
+}
+
+// hijackRecorder is a [httptest.ResponseRecorder] which supports [http.Hijacker].
+type hijackRecorder struct {
+	*httptest.ResponseRecorder
+	conn net.Conn
+}
+
+func (r *hijackRecorder) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	return r.conn, bufio.NewReadWriter(bufio.NewReader(r.conn), bufio.NewWriter(r.conn)), nil
+}
+
+func TestLoggingMiddleware_hijack(t *testing.T) {
+	var buf bytes.Buffer
+	mw := LoggingMiddleware(slog.New(NewHandler(&buf)))
+	handler := mw(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, _, err := w.(http.Hijacker).Hijack()
+		if err != nil {
+			t.Fatalf("Hijack() = %v", err)
+		}
+		conn.Close()
+	}))
+	server, client := net.Pipe()
+	defer client.Close()
+	handler.ServeHTTP(&hijackRecorder{ResponseRecorder: httptest.NewRecorder(), conn: server}, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	var got struct {
+		HTTPRequest struct {
+			Status int `json:"status"`
+		} `json:"httpRequest"`
+	}
+	if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+		t.Fatalf("Failed to decode log output: %v", err)
+	}
+	if got.HTTPRequest.Status != http.StatusSwitchingProtocols {
+		t.Errorf("status = %d, want %d", got.HTTPRequest.Status, http.StatusSwitchingProtocols)
+	}
+
+	// a ResponseWriter without Hijack support
+	handler = mw(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, _, err := w.(http.Hijacker).Hijack(); err == nil {
+			t.Error("Hijack() succeeded, want an error")
+		}
+	}))
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+}
@@ -0,0 +1,57 @@
+package sloggcp
+
+import "log/slog"
+
+// OperationKey is the special field for information about an operation
+// the log entry is associated with.
+// See https://cloud.google.com/logging/docs/reference/v2/rest/v2/LogEntry#LogEntryOperation.
+const OperationKey = "logging.googleapis.com/operation"
+
+// Members of the [OperationKey] object.
+const (
+	OperationIDKey       = "id"
+	OperationProducerKey = "producer"
+	OperationFirstKey    = "first"
+	OperationLastKey     = "last"
+)
+
+// Operation returns an attribute for the [OperationKey] special field,
+// which groups the log entries of a long-running operation, such as a request.
+// The id identifies the operation and must not be empty,
+// otherwise the zero [slog.Attr] is returned, which is dropped by the handler.
+// The producer should be a reverse-DNS style string, such as "github.com/MyProject/MyApplication".
+// Set first for the first and last for the last entry of the operation.
+// False values are omitted.
+//
+// The handler always emits the field at the top level, even when logged inside a group.
+func Operation(id, producer string, first, last bool) slog.Attr {
+	if id == "" {
+		return slog.Attr{}
+	}
+	attrs := make([]slog.Attr, 0, 4)
+	attrs = append(attrs, slog.String(OperationIDKey, id))
+	if producer != "" {
+		attrs = append(attrs, slog.String(OperationProducerKey, producer))
+	}
+	if first {
+		attrs = append(attrs, slog.Bool(OperationFirstKey, true))
+	}
+	if last {
+		attrs = append(attrs, slog.Bool(OperationLastKey, true))
+	}
+	return slog.Attr{Key: OperationKey, Value: slog.GroupValue(attrs...)}
+}
+
+// checkAndSetOperation sets the [OperationKey] object in out,
+// at any group depth. It returns false if a is not an operation group attribute.
+func checkAndSetOperation(a slog.Attr, out map[string]any) bool {
+	if a.Key != OperationKey {
+		return false
+	}
+	v := a.Value.Resolve()
+	if v.Kind() != slog.KindGroup {
+		return false
+	}
+	out[OperationKey] = extractValue(v)
+	return true
+}
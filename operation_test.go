@@ -0,0 +1,60 @@
+package sloggcp
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"reflect"
+	"testing"
+)
+
+func TestOperation(t *testing.T) {
+	tests := []struct {
+		name string
+		attr slog.Attr
+		want any
+	}{
+		{
+			name: "first",
+			attr: Operation("op-1", "github.com/muhlemmer/sloggcp", true, false),
+			want: map[string]any{"id": "op-1", "producer": "github.com/muhlemmer/sloggcp", "first": true},
+		},
+		{
+			name: "progress",
+			attr: Operation("op-1", "github.com/muhlemmer/sloggcp", false, false),
+			want: map[string]any{"id": "op-1", "producer": "github.com/muhlemmer/sloggcp"},
+		},
+		{
+			name: "last",
+			attr: Operation("op-1", "", false, true),
+			want: map[string]any{"id": "op-1", "last": true},
+		},
+		{
+			name: "inside group",
+			attr: slog.Group("group", Operation("op-1", "", false, false)),
+			want: map[string]any{"id": "op-1"},
+		},
+		{
+			name: "empty id",
+			attr: Operation("", "github.com/muhlemmer/sloggcp", true, true),
+			want: nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			slog.New(NewHandler(&buf)).Info("test message", tt.attr)
+
+			var got map[string]any
+			if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+				t.Fatalf("Failed to decode log output: %v", err)
+			}
+			if !reflect.DeepEqual(got[OperationKey], tt.want) {
+				t.Errorf("%s = %v, want %v", OperationKey, got[OperationKey], tt.want)
+			}
+			if _, ok := got[""]; ok {
+				t.Errorf("empty key in output: %v", got)
+			}
+		})
+	}
+}
@@ -0,0 +1,449 @@
+package sloggcp
+
+import (
+	"io"
+	"log/slog"
+	"slices"
+	"time"
+)
+
+// Option configures the handler returned by [NewHandler] or [NewErrorReportingHandler].
+type Option func(*config)
+
+// config holds the settings applied by [Option] functions.
+// It is shared between a handler and its derivatives and must not be modified after construction.
+type config struct {
+	handlerOptions      slog.HandlerOptions
+	projectID           string
+	recordStack         bool
+	serviceContext      *ServiceContext
+	nestedReportContext bool
+	errorKeys           []string
+	preserveMessage     bool
+	recordLocation      bool
+	timestamp           timestampMode
+	sourceTrimPrefix    string
+	errorReportMinLevel slog.Leveler
+	severityMapper      func(slog.Level) string
+	redactKeys          map[string]struct{}
+	labelWarning        func(key string, value slog.Value)
+	autoInsertID        bool
+	writer              io.Writer
+	contextAttrsGroup   string
+	newline             bool
+	devMode             bool
+	payloadGroup        string
+	reservedKeyPolicy   ReservedKeyPolicy
+	tees                []io.Writer
+	sampling            map[slog.Level]*sampler
+	dedup               *deduplicator
+	errorReportType     string
+	processLabels       bool
+	errorReportCallback func(slog.Record)
+	clock               func() time.Time
+	numericSeverity     bool
+	sourceMinLevel      slog.Leveler
+	errorChain          bool
+	component           string
+	syslogPrefix        bool
+	syslogFacility      int
+	stackKeys           []string
+	timeKeyName         string
+	maxStackSize        int
+	errorSource         bool
+	resourceLabels      map[string]string
+	indentPrefix        string
+	indent              string
+	shortFunction       bool
+	lowercaseSeverity   bool
+	writerFunc          func(slog.Level) io.Writer
+	utc                 bool
+}
+
+func newConfig(options []Option) *config {
+	c := &config{
+		handlerOptions:  DefaultOpts,
+		errorKeys:       []string{ErrorKey},
+		severityMapper:  SeverityFromLevel,
+		newline:         true,
+		errorReportType: ErrorReportTypeValue,
+		clock:           time.Now,
+		maxStackSize:    DefaultMaxStackSize,
+	}
+	for _, option := range options {
+		option(c)
+	}
+	if c.handlerOptions.Level == nil {
+		c.handlerOptions.Level = DefaultOpts.Level
+	}
+	return c
+}
+
+// WithHandlerOptions sets the standard [slog.HandlerOptions].
+// The options are copied. When opts is nil, [DefaultOpts] is used.
+// A nil Level defaults to the Level of [DefaultOpts].
+func WithHandlerOptions(opts *slog.HandlerOptions) Option {
+	return func(c *config) {
+		if opts == nil {
+			opts = &DefaultOpts
+		}
+		c.handlerOptions = *opts
+	}
+}
+
+// WithMinLevel sets the minimum level of records to be logged.
+// It overrides the Level of the [slog.HandlerOptions].
+// The level is checked for every record, so a [slog.LevelVar] can change it at runtime.
+func WithMinLevel(level slog.Leveler) Option {
+	return func(c *config) {
+		c.handlerOptions.Level = level
+	}
+}
+
+// WithProjectID sets the GCP project ID.
+// It is used to build the fully qualified trace name
+// in the form of "projects/PROJECT_ID/traces/TRACE_ID".
+func WithProjectID(projectID string) Option {
+	return func(c *config) {
+		c.projectID = projectID
+	}
+}
+
+// WithRecordStack enables stack traces for errors which don't implement [StackTraceError].
+// The stack trace is taken from the call stack at the time of logging,
+// starting at the program counter of the [slog.Record].
+// It is disabled by default.
+func WithRecordStack(enable bool) Option {
+	return func(c *config) {
+		c.recordStack = enable
+	}
+}
+
+// WithMaxStackSize limits the error report message, which embeds the stack trace, to size bytes.
+// Longer messages are cut after the last complete line that fits
+// and end with [TruncatedMarker], so that the top frames used for grouping are preserved.
+// The default is [DefaultMaxStackSize]. A size of 0 or less disables the limit.
+func WithMaxStackSize(size int) Option {
+	return func(c *config) {
+		c.maxStackSize = size
+	}
+}
+
+// WithServiceContext adds the [ServiceContextKey] object to every error report,
+// so that Error Reporting can group errors by service and version.
+// The version is omitted when empty.
+func WithServiceContext(service, version string) Option {
+	return func(c *config) {
+		c.serviceContext = &ServiceContext{
+			Service: service,
+			Version: version,
+		}
+	}
+}
+
+// WithNestedReportContext places the [ReportLocationKey] object inside the
+// [ErrorContextKey] object, as defined by the Error Reporting format.
+// By default, the report location is written at the top level for backward compatibility.
+func WithNestedReportContext(enable bool) Option {
+	return func(c *config) {
+		c.nestedReportContext = enable
+	}
+}
+
+// WithErrorKey sets the attribute key by which errors are detected.
+// It defaults to [ErrorKey].
+func WithErrorKey(key string) Option {
+	return WithErrorKeys(key)
+}
+
+// WithErrorKeys sets multiple attribute keys by which errors are detected.
+// When a record contains more than one of the keys,
+// the key that comes first in keys is used for the error report.
+func WithErrorKeys(keys ...string) Option {
+	return func(c *config) {
+		c.errorKeys = slices.Clone(keys)
+	}
+}
+
+// WithPreserveMessage keeps the log message of error reports,
+// by prefixing the error details in the message attribute as "<message>: <error details>".
+// By default, the log message is replaced by the error details.
+func WithPreserveMessage(enable bool) Option {
+	return func(c *config) {
+		c.preserveMessage = enable
+	}
+}
+
+// WithRecordLocation enables the report location of errors
+// which don't provide a [ReportLocationError] or a stack trace.
+// The report location is then derived from the program counter of the [slog.Record],
+// which is the log call site.
+// It is not added when the record already has a source location, because of AddSource.
+// It is disabled by default.
+func WithRecordLocation(enable bool) Option {
+	return func(c *config) {
+		c.recordLocation = enable
+	}
+}
+
+// WithTimestamp renames the time attribute to [TimestampKey],
+// for ingestion paths which expect the "timestamp" field.
+// When object is true, the time is rendered as an object with
+// "seconds" and "nanos" members, instead of a RFC3339 string.
+// The option applies to the handler and to [NewReplaceAttr].
+func WithTimestamp(object bool) Option {
+	return func(c *config) {
+		if object {
+			c.timestamp = timestampObject
+		} else {
+			c.timestamp = timestampString
+		}
+	}
+}
+
+// WithTimeKey sets the key of the time attribute.
+// By default, the key is [TimeKey], or [TimestampKey] when [WithTimestamp] is used.
+// The format of the time is not affected, as it is set by [WithTimestamp].
+// Top-level attributes with the key are subject to the [ReservedKeyPolicy].
+// An empty key restores the default.
+// The option applies to the handler and to [NewReplaceAttr].
+func WithTimeKey(key string) Option {
+	return func(c *config) {
+		c.timeKeyName = key
+	}
+}
+
+// WithSourceTrimPrefix trims prefix from the file path of the source location,
+// such as the build directory, to avoid leaking build machine paths.
+// The option applies to the handler and to [NewReplaceAttr].
+func WithSourceTrimPrefix(prefix string) Option {
+	return func(c *config) {
+		c.sourceTrimPrefix = prefix
+	}
+}
+
+// WithSourceFunctionShortening removes the package path from the function of the source location,
+// so that "github.com/x/y.Foo" becomes "Foo", to avoid leaking the internal structure of the program.
+// Methods keep their receiver, such as "(*T).Method".
+// The option applies to the handler and to [NewReplaceAttr].
+func WithSourceFunctionShortening(enable bool) Option {
+	return func(c *config) {
+		c.shortFunction = enable
+	}
+}
+
+// WithSourceMinLevel adds the "logging.googleapis.com/sourceLocation" ([SourceLocationKey]) field
+// only to records at or above level, as resolving the source of every record is expensive.
+// It replaces the AddSource setting of the [slog.HandlerOptions]:
+// records at or above level get a source location, even if AddSource is false.
+func WithSourceMinLevel(level slog.Leveler) Option {
+	return func(c *config) {
+		c.sourceMinLevel = level
+	}
+}
+
+// WithErrorSource adds the "logging.googleapis.com/sourceLocation" ([SourceLocationKey]) field to error reports,
+// even if the record doesn't get one from AddSource or [WithSourceMinLevel].
+// The source is resolved from the program counter of the [slog.Record] only for error reports,
+// so that other records don't pay the cost.
+// It is disabled by default.
+func WithErrorSource(enable bool) Option {
+	return func(c *config) {
+		c.errorSource = enable
+	}
+}
+
+// WithErrorReportMinLevel sets the minimum level of records to be promoted to error reports.
+// Below level, the error attribute is logged as an ordinary field.
+// By default, every record with an error attribute is reported.
+func WithErrorReportMinLevel(level slog.Leveler) Option {
+	return func(c *config) {
+		c.errorReportMinLevel = level
+	}
+}
+
+// WithErrorReportType sets the [ErrorReportTypeKey] value of error reports.
+// By default, [ErrorReportTypeValue] is used. An empty typeURL restores the default.
+func WithErrorReportType(typeURL string) Option {
+	return func(c *config) {
+		if typeURL == "" {
+			typeURL = ErrorReportTypeValue
+		}
+		c.errorReportType = typeURL
+	}
+}
+
+// WithErrorChain adds the "causes" ([ErrorCausesKey]) field next to the error attribute,
+// with the Error() strings of the wrapped errors in unwrap order, deepest last.
+// The message and error fields are not affected.
+// An attribute with the key next to the error attribute is kept, and the field is not added.
+// Errors which wrap multiple errors, such as created by [errors.Join], end the chain.
+func WithErrorChain(enable bool) Option {
+	return func(c *config) {
+		c.errorChain = enable
+	}
+}
+
+// WithErrorReportCallback sets a function which is called for every record
+// which is promoted to an error report, for example to count reported errors.
+// The function is called synchronously, before the record is written.
+// It receives a clone of the record, so that it can't modify the output.
+func WithErrorReportCallback(fn func(record slog.Record)) Option {
+	return func(c *config) {
+		c.errorReportCallback = fn
+	}
+}
+
+// WithUTC converts the record time to UTC before it is formatted,
+// for the time field of [WithTimestamp] and [WithTimeKey], the [EventTimeKey] field
+// and the time of the dev output,
+// so that timestamps have a "Z" suffix instead of the local offset.
+// GCP stores timestamps in UTC, so the offset carries no information.
+// The option applies to the handler and to [NewReplaceAttr]. It is disabled by default.
+func WithUTC(enable bool) Option {
+	return func(c *config) {
+		c.utc = enable
+	}
+}
+
+// WithClock sets the function which provides the current time,
+// used when a time is required but the record time is zero, such as for the [EventTimeKey] field.
+// By default, [time.Now] is used. A nil clock restores the default.
+// This is mainly useful for deterministic output in tests.
+func WithClock(clock func() time.Time) Option {
+	return func(c *config) {
+		if clock == nil {
+			clock = time.Now
+		}
+		c.clock = clock
+	}
+}
+
+// WithNumericSeverity emits the severity field as number of the google.logging.type.LogSeverity enum,
+// such as 400 for [WarningSeverity], instead of the severity string.
+// Severities are mapped by [SeverityNumber], after the mapper set by [WithSeverityMapper].
+// Numbers are accepted by the Cloud Logging API when entries are written as protobuf JSON.
+// The option applies to the handler and to [NewReplaceAttr].
+func WithNumericSeverity(enable bool) Option {
+	return func(c *config) {
+		c.numericSeverity = enable
+	}
+}
+
+// WithLowercaseSeverity emits the severity in lower case, such as "error" instead of [ErrorSeverity],
+// for log processors which expect lowercase severities. Cloud Logging accepts either case.
+// The option applies to the handler and to [NewReplaceAttr].
+// It has no effect when [WithNumericSeverity] is enabled.
+func WithLowercaseSeverity(enable bool) Option {
+	return func(c *config) {
+		c.lowercaseSeverity = enable
+	}
+}
+
+// WithSeverityMapper sets the function which maps levels to the severity field value.
+// By default, [SeverityFromLevel] is used. A nil mapper restores the default.
+// The option applies to the handler and to [NewReplaceAttr].
+func WithSeverityMapper(mapper func(slog.Level) string) Option {
+	return func(c *config) {
+		if mapper == nil {
+			mapper = SeverityFromLevel
+		}
+		c.severityMapper = mapper
+	}
+}
+
+// WithRedactKeys replaces the values of attributes with one of keys by [RedactedValue].
+// Keys are matched against the leaf key, at any group depth,
+// including groups from [slog.LogValuer] values, maps in slices, [Labels] and the error value.
+// The entries of joined errors are matched by their "message" and "stackTrace" keys.
+// Multiple calls add to the set of redacted keys.
+func WithRedactKeys(keys ...string) Option {
+	return func(c *config) {
+		if c.redactKeys == nil {
+			c.redactKeys = make(map[string]struct{}, len(keys))
+		}
+		for _, key := range keys {
+			c.redactKeys[key] = struct{}{}
+		}
+	}
+}
+
+// WithAutoInsertID generates an [InsertIDKey] field for each record without an [InsertID] attribute.
+// The generated ID is unique per record, so that retried writes of the same entry are deduplicated.
+func WithAutoInsertID() Option {
+	return func(c *config) {
+		c.autoInsertID = true
+	}
+}
+
+// WithContextAttrsGroup adds the attributes from [ContextWithAttrs] to the group name,
+// instead of the top level.
+func WithContextAttrsGroup(name string) Option {
+	return func(c *config) {
+		c.contextAttrsGroup = name
+	}
+}
+
+// WithNewline controls the newline which terminates each record written by [NewHandler].
+// It is enabled by default, producing newline delimited JSON.
+// Disable it when the writer does its own framing.
+func WithNewline(enable bool) Option {
+	return func(c *config) {
+		c.newline = enable
+	}
+}
+
+// WithIndent makes [NewHandler] pretty-print each record, as by [json.MarshalIndent],
+// for local debugging. Each record is still written with a single write.
+// The output spans multiple lines, which Cloud Logging doesn't parse as structured logs,
+// so it is disabled by default. Empty prefix and indent restore the compact output.
+func WithIndent(prefix, indent string) Option {
+	return func(c *config) {
+		c.indentPrefix, c.indent = prefix, indent
+	}
+}
+
+// WithPayloadGroup nests all attributes under the group name,
+// like an implicit [slog.Logger.WithGroup].
+// Special fields, such as the severity, message, time, trace and
+// error reporting fields, are kept at the top level.
+func WithPayloadGroup(name string) Option {
+	return func(c *config) {
+		c.payloadGroup = name
+	}
+}
+
+// WithReservedKeyPolicy sets how top-level attributes with a key of a field emitted by the handler,
+// such as "severity", "message" or "time", are treated.
+// By default they are renamed, see [ReservedKeyRename].
+func WithReservedKeyPolicy(policy ReservedKeyPolicy) Option {
+	return func(c *config) {
+		c.reservedKeyPolicy = policy
+	}
+}
+
+// WithWriterFunc makes [NewHandler] write each record to the writer returned by fn for the level of the record,
+// instead of the writer passed to [NewHandler], such as [os.Stderr] for errors and [os.Stdout] otherwise.
+// If fn returns nil, the writer passed to [NewHandler] is used.
+// Each record is written with a single write, and writes are serialized across all writers.
+// The tees of [WithTees] still receive all records.
+// Flush and Close of the handler don't apply to the writers returned by fn.
+// In dev mode, the human readable lines are routed the same way.
+func WithWriterFunc(fn func(level slog.Level) io.Writer) Option {
+	return func(c *config) {
+		c.writerFunc = fn
+	}
+}
+
+// WithTees writes each record of [NewHandler] to the writers w as well,
+// in addition to the writer passed to [NewHandler].
+// A failing writer doesn't prevent the other writers from receiving the record;
+// the errors of all writers are joined.
+// Flush and Close of the handler apply to all writers.
+// Multiple calls add to the list of writers.
+// In dev mode, the tees receive the same human readable lines as the writer.
+func WithTees(w ...io.Writer) Option {
+	return func(c *config) {
+		c.tees = append(c.tees, w...)
+	}
+}
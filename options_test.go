@@ -0,0 +1,144 @@
+package sloggcp
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"reflect"
+	"testing"
+)
+
+func TestNewHandler(t *testing.T) {
+	tests := []struct {
+		name    string
+		options []Option
+		level   slog.Level
+		want    *expectSchema
+	}{
+		{
+			name:    "defaults, debug disabled",
+			options: nil,
+			level:   slog.LevelDebug,
+			want:    nil,
+		},
+		{
+			name:    "defaults, info enabled",
+			options: nil,
+			level:   slog.LevelInfo,
+			want: &expectSchema{
+				Message:  "test message",
+				Severity: InfoSeverity,
+			},
+		},
+		{
+			name:    "min level",
+			options: []Option{WithMinLevel(slog.LevelDebug)},
+			level:   slog.LevelDebug,
+			want: &expectSchema{
+				Message:  "test message",
+				Severity: DebugSeverity,
+			},
+		},
+		{
+			name: "min level overrides handler options",
+			options: []Option{
+				WithHandlerOptions(&slog.HandlerOptions{Level: slog.LevelDebug}),
+				WithMinLevel(slog.LevelWarn),
+			},
+			level: slog.LevelInfo,
+			want:  nil,
+		},
+		{
+			name: "handler options",
+			options: []Option{
+				WithHandlerOptions(&slog.HandlerOptions{AddSource: true}),
+			},
+			level: slog.LevelInfo,
+			want: &expectSchema{
+				Message:  "test message",
+				Severity: InfoSeverity,
+				Source: testSource{
+					Function: "github.com/muhlemmer/sloggcp.TestNewHandler.func1",
+				},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			logger := slog.New(NewHandler(&buf, tt.options...))
+			logger.Log(t.Context(), tt.level, "test message")
+			if tt.want == nil {
+				if buf.Len() != 0 {
+					t.Errorf("log wrote data, but want is nil: %q", buf.String())
+				}
+				return
+			}
+			var got expectSchema
+			if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+				t.Fatalf("Failed to decode log output: %v", err)
+			}
+			if !reflect.DeepEqual(&got, tt.want) {
+				t.Errorf("log output = %+v, want %+v", &got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWithHandlerOptions_copy(t *testing.T) {
+	opts := &slog.HandlerOptions{}
+	NewErrorReportingHandler(&bytes.Buffer{}, opts)
+	if opts.Level != nil {
+		t.Errorf("NewErrorReportingHandler() modified opts.Level to %v", opts.Level)
+	}
+}
+
+func TestHandler_levelVar(t *testing.T) {
+	tests := []struct {
+		name       string
+		newHandler func(w *bytes.Buffer, level slog.Leveler) slog.Handler
+	}{
+		{
+			name: "NewErrorReportingHandler",
+			newHandler: func(w *bytes.Buffer, level slog.Leveler) slog.Handler {
+				return NewErrorReportingHandler(w, &slog.HandlerOptions{Level: level})
+			},
+		},
+		{
+			name: "WithMinLevel",
+			newHandler: func(w *bytes.Buffer, level slog.Leveler) slog.Handler {
+				return NewHandler(w, WithMinLevel(level))
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var (
+				buf   bytes.Buffer
+				level slog.LevelVar
+			)
+			logger := slog.New(tt.newHandler(&buf, &level))
+			derived := logger.With("foo", "bar").WithGroup("group")
+
+			logger.Debug("dropped")
+			derived.Debug("dropped")
+			if buf.Len() != 0 {
+				t.Fatalf("debug logged at level %v: %s", level.Level(), buf.String())
+			}
+
+			level.Set(slog.LevelDebug)
+			logger.Debug("logged")
+			derived.Debug("logged")
+			if n := bytes.Count(buf.Bytes(), []byte("\n")); n != 2 {
+				t.Errorf("records after level change = %d, want 2\n%s", n, buf.String())
+			}
+
+			buf.Reset()
+			level.Set(slog.LevelWarn)
+			logger.Info("dropped")
+			if buf.Len() != 0 {
+				t.Errorf("info logged at level %v: %s", level.Level(), buf.String())
+			}
+		})
+	}
+}
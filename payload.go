@@ -0,0 +1,59 @@
+package sloggcp
+
+import (
+	"maps"
+	"slices"
+)
+
+// specialKeys are the keys of the fields the handler emits at the top level,
+// as they are interpreted by Cloud Logging or Error Reporting.
+var specialKeys = map[string]bool{
+	SeverityKey:        true,
+	MessageKey:         true,
+	TimeKey:            true,
+	TimestampKey:       true,
+	SourceLocationKey:  true,
+	TraceKey:           true,
+	SpanIDKey:          true,
+	TraceSampledKey:    true,
+	LabelsKey:          true,
+	InsertIDKey:        true,
+	OperationKey:       true,
+	HTTPRequestKey:     true,
+	ErrorReportTypeKey: true,
+	ReportLocationKey:  true,
+	ServiceContextKey:  true,
+	EventTimeKey:       true,
+	ErrorContextKey:    true,
+}
+
+// SpecialKeys returns the sorted keys of the fields which are interpreted by Cloud Logging or Error Reporting,
+// such as [SeverityKey], [TraceKey] and [ErrorReportTypeKey].
+// The handler emits them at the top level. Attributes with these keys are handled specially,
+// or treated by the [ReservedKeyPolicy] if the handler emits the field itself, such as [MessageKey].
+// The returned slice may be modified by the caller.
+func SpecialKeys() []string {
+	return slices.Sorted(maps.Keys(specialKeys))
+}
+
+// groupPayload moves all fields of out which are no special fields
+// into the group set by [WithPayloadGroup].
+func (c *config) groupPayload(out map[string]any) {
+	if c.payloadGroup == "" {
+		return
+	}
+	var payload map[string]any
+	for k, v := range out {
+		if specialKeys[k] || k == c.timeKey() {
+			continue
+		}
+		if payload == nil {
+			payload = make(map[string]any)
+		}
+		payload[k] = v
+		delete(out, k)
+	}
+	if payload != nil {
+		out[c.payloadGroup] = payload
+	}
+}
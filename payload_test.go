@@ -0,0 +1,103 @@
+package sloggcp
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"reflect"
+	"slices"
+	"testing"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestWithPayloadGroup(t *testing.T) {
+	ctx := trace.ContextWithSpanContext(context.Background(), trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID: testTraceID,
+		SpanID:  testSpanID,
+	}))
+	tests := []struct {
+		name       string
+		log        func(*slog.Logger)
+		wantTop    []string
+		wantNested map[string]any
+	}{
+		{
+			name: "info",
+			log: func(l *slog.Logger) {
+				l.With("foo", "bar").InfoContext(ctx, "test message", "count", 1, Labels("region", "eu"))
+			},
+			wantTop:    []string{SeverityKey, MessageKey, TimeKey, TraceKey, SpanIDKey, TraceSampledKey, LabelsKey},
+			wantNested: map[string]any{"foo": "bar", "count": float64(1)},
+		},
+		{
+			name: "error",
+			log: func(l *slog.Logger) {
+				l.Error("test message", "error", mockReportLocationError{}, slog.Group("group", "foo", "bar"))
+			},
+			wantTop: []string{SeverityKey, MessageKey, TimeKey, ErrorReportTypeKey, EventTimeKey, ReportLocationKey},
+			wantNested: map[string]any{
+				"error": "mockReportLocationError",
+				"group": map[string]any{"foo": "bar"},
+			},
+		},
+		{
+			name: "no attributes",
+			log: func(l *slog.Logger) {
+				l.Info("test message")
+			},
+			wantTop: []string{SeverityKey, MessageKey, TimeKey},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			tt.log(slog.New(NewHandler(&buf, WithPayloadGroup("payload"))))
+
+			var got map[string]any
+			if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+				t.Fatalf("Failed to decode log output: %v", err)
+			}
+			for _, key := range tt.wantTop {
+				if _, ok := got[key]; !ok {
+					t.Errorf("%s missing at the top level: %v", key, got)
+				}
+				delete(got, key)
+			}
+			var wantRest map[string]any
+			if tt.wantNested != nil {
+				wantRest = map[string]any{"payload": tt.wantNested}
+			} else {
+				wantRest = map[string]any{}
+			}
+			if !reflect.DeepEqual(got, wantRest) {
+				t.Errorf("log output = %v, want %v", got, wantRest)
+			}
+		})
+	}
+}
+
+func TestSpecialKeys(t *testing.T) {
+	got := SpecialKeys()
+	if !slices.IsSorted(got) {
+		t.Errorf("SpecialKeys() = %v, want sorted", got)
+	}
+	for _, key := range []string{
+		SeverityKey, MessageKey, TimeKey, SourceLocationKey, TraceKey, SpanIDKey,
+		LabelsKey, OperationKey, InsertIDKey, ErrorReportTypeKey, ReportLocationKey,
+	} {
+		if !slices.Contains(got, key) {
+			t.Errorf("SpecialKeys() = %v, missing %q", got, key)
+		}
+	}
+	for key := range reservedKeys {
+		if !slices.Contains(got, key) {
+			t.Errorf("SpecialKeys() = %v, missing reserved key %q", got, key)
+		}
+	}
+	got[0] = "modified"
+	if SpecialKeys()[0] == "modified" {
+		t.Error("SpecialKeys() returned shared slice")
+	}
+}
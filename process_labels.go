@@ -0,0 +1,53 @@
+package sloggcp
+
+import (
+	"bytes"
+	"os"
+	"runtime"
+	"strconv"
+)
+
+// Labels set by [WithProcessLabels].
+const (
+	PIDLabel       = "pid"
+	GoroutineLabel = "goroutine"
+)
+
+// WithProcessLabels adds the process ID and the ID of the logging goroutine
+// to the [LabelsKey] object of every record, as [PIDLabel] and [GoroutineLabel].
+// The goroutine ID is parsed from the first line of a small stack trace
+// and is omitted if that fails.
+// Labels set by attributes override the process labels.
+func WithProcessLabels() Option {
+	return func(c *config) {
+		c.processLabels = true
+	}
+}
+
+var pidLabel = strconv.Itoa(os.Getpid())
+
+// setProcessLabels sets the labels of [WithProcessLabels] in labels.
+func setProcessLabels(labels map[string]string) {
+	labels[PIDLabel] = pidLabel
+	if id, ok := goroutineID(); ok {
+		labels[GoroutineLabel] = id
+	}
+}
+
+var goroutinePrefix = []byte("goroutine ")
+
+// goroutineID returns the ID of the calling goroutine,
+// from the first line of its stack trace: "goroutine 123 [running]:".
+func goroutineID() (string, bool) {
+	var buf [64]byte
+	b := buf[:runtime.Stack(buf[:], false)]
+	b, ok := bytes.CutPrefix(b, goroutinePrefix)
+	if !ok {
+		return "", false
+	}
+	id, _, ok := bytes.Cut(b, []byte{' '})
+	if !ok || len(id) == 0 {
+		return "", false
+	}
+	return string(id), true
+}
@@ -0,0 +1,67 @@
+package sloggcp
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"maps"
+	"os"
+	"strconv"
+	"testing"
+)
+
+func TestWithProcessLabels(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(NewHandler(&buf, WithProcessLabels()))
+	logger.Info("test message", Labels("tenant", "acme", GoroutineLabel, "override"))
+	logger.Info("test message")
+
+	type labelsSchema struct {
+		Labels map[string]string `json:"logging.googleapis.com/labels"`
+	}
+	var got []labelsSchema
+	for line := range bytes.Lines(buf.Bytes()) {
+		var record labelsSchema
+		if err := json.Unmarshal(line, &record); err != nil {
+			t.Fatalf("Failed to decode log output: %v", err)
+		}
+		got = append(got, record)
+	}
+	if len(got) != 2 {
+		t.Fatalf("records = %d, want 2", len(got))
+	}
+	want := map[string]string{
+		PIDLabel:       strconv.Itoa(os.Getpid()),
+		GoroutineLabel: "override",
+		"tenant":       "acme",
+	}
+	if labels := got[0].Labels; !maps.Equal(labels, want) {
+		t.Errorf("labels = %v, want %v", labels, want)
+	}
+	labels := got[1].Labels
+	if labels[PIDLabel] != want[PIDLabel] {
+		t.Errorf("pid label = %q, want %q", labels[PIDLabel], want[PIDLabel])
+	}
+	if _, err := strconv.ParseUint(labels[GoroutineLabel], 10, 64); err != nil {
+		t.Errorf("goroutine label = %q: %v", labels[GoroutineLabel], err)
+	}
+}
+
+func BenchmarkHandle_processLabels(b *testing.B) {
+	for _, bb := range []struct {
+		name    string
+		options []Option
+	}{
+		{"default", nil},
+		{"process labels", []Option{WithProcessLabels()}},
+	} {
+		b.Run(bb.name, func(b *testing.B) {
+			logger := slog.New(NewHandler(io.Discard, bb.options...))
+			b.ReportAllocs()
+			for b.Loop() {
+				logger.Info("benchmark message", "count", 42)
+			}
+		})
+	}
+}
@@ -0,0 +1,95 @@
+package sloggcp
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"runtime"
+	"runtime/debug"
+	"strings"
+	"time"
+)
+
+// RecoverOption configures [RecoverAndLog].
+type RecoverOption func(*recoverConfig)
+
+type recoverConfig struct {
+	repanic bool
+}
+
+// WithRepanic makes [RecoverAndLog] panic again with the recovered value,
+// after it has been logged. By default the panic is swallowed.
+func WithRepanic(enable bool) RecoverOption {
+	return func(c *recoverConfig) {
+		c.repanic = enable
+	}
+}
+
+// RecoverAndLog recovers from a panic and logs it at [LevelCritical],
+// with a [StackTraceError] under [ErrorKey], so that the panic is reported to Error Reporting
+// with the stack trace of the panicking goroutine.
+// The source location of the record is the location of the panic.
+// It must be called directly by a defer statement:
+//
+//	defer sloggcp.RecoverAndLog(logger)
+//
+// Nothing is logged if there is no panic.
+func RecoverAndLog(logger *slog.Logger, options ...RecoverOption) {
+	v := recover()
+	if v == nil {
+		return
+	}
+	var c recoverConfig
+	for _, option := range options {
+		option(&c)
+	}
+	ctx := context.Background()
+	if logger.Enabled(ctx, LevelCritical) {
+		r := slog.NewRecord(time.Now(), LevelCritical, "panic recovered", panicPC())
+		r.AddAttrs(slog.Any(ErrorKey, &panicError{
+			value: v,
+			stack: debug.Stack(),
+		}))
+		_ = logger.Handler().Handle(ctx, r)
+	}
+	if c.repanic {
+		panic(v)
+	}
+}
+
+// panicPC returns the program counter of the panic, for the source location of [RecoverAndLog].
+// It is the first caller of the deferred RecoverAndLog outside of the runtime,
+// which calls the deferred functions while panicking.
+func panicPC() uintptr {
+	var pcs [32]uintptr
+	n := runtime.Callers(3, pcs[:]) // skip [runtime.Callers, panicPC, RecoverAndLog]
+	for _, pc := range pcs[:n] {
+		frame, _ := runtime.CallersFrames([]uintptr{pc}).Next()
+		if !strings.HasPrefix(frame.Function, "runtime.") {
+			return pc
+		}
+	}
+	return 0
+}
+
+// panicError is a [StackTraceError] for a recovered panic value.
+type panicError struct {
+	value any
+	stack []byte
+}
+
+func (e *panicError) Error() string {
+	return fmt.Sprintf("panic: %v", e.value)
+}
+
+// StackTrace returns the stack trace, preceded by the panic message,
+// as expected by Error Reporting for the message of a reported error.
+func (e *panicError) StackTrace() []byte {
+	return append([]byte(e.Error()+"\n\n"), e.stack...)
+}
+
+// Unwrap returns the panic value, if it is an error.
+func (e *panicError) Unwrap() error {
+	err, _ := e.value.(error)
+	return err
+}
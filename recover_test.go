@@ -0,0 +1,92 @@
+package sloggcp
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRecoverAndLog(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(NewHandler(&buf))
+	func() {
+		defer RecoverAndLog(logger)
+		panic("boom")
+	}()
+
+	var got expectSchema
+	if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+		t.Fatalf("Failed to decode log output: %v", err)
+	}
+	if got.Type != ErrorReportTypeValue {
+		t.Errorf("@type = %q, want %q", got.Type, ErrorReportTypeValue)
+	}
+	if got.Severity != CriticalSeverity {
+		t.Errorf("severity = %q, want %q", got.Severity, CriticalSeverity)
+	}
+	if got.Error != "panic: boom" {
+		t.Errorf("error = %v, want %q", got.Error, "panic: boom")
+	}
+	if !strings.HasPrefix(got.Message, "panic: boom\n\ngoroutine ") || !strings.Contains(got.Message, "TestRecoverAndLog") {
+		t.Errorf("message = %q, want stack trace of the panic", got.Message)
+	}
+}
+
+func TestRecoverAndLog_source(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(NewHandler(&buf, WithHandlerOptions(&slog.HandlerOptions{AddSource: true})))
+	func() {
+		defer RecoverAndLog(logger)
+		var m map[string]int
+		m["boom"] = 1 // runtime panic
+	}()
+
+	var got struct {
+		Source slog.Source `json:"logging.googleapis.com/sourceLocation"`
+	}
+	if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+		t.Fatalf("Failed to decode log output: %v", err)
+	}
+	if file := filepath.Base(got.Source.File); file != "recover_test.go" {
+		t.Errorf("source file = %q, want %q", file, "recover_test.go")
+	}
+	if !strings.HasPrefix(got.Source.Function, "github.com/muhlemmer/sloggcp.TestRecoverAndLog_source") {
+		t.Errorf("source function = %q, want the test function", got.Source.Function)
+	}
+}
+
+func TestRecoverAndLog_noPanic(t *testing.T) {
+	var buf bytes.Buffer
+	func() {
+		defer RecoverAndLog(slog.New(NewHandler(&buf)))
+	}()
+	if buf.Len() != 0 {
+		t.Errorf("log output = %s, want none", buf.String())
+	}
+}
+
+func TestRecoverAndLog_repanic(t *testing.T) {
+	errBoom := errors.New("boom")
+	defer func() {
+		if v := recover(); v != errBoom {
+			t.Errorf("recovered %v, want %v", v, errBoom)
+		}
+	}()
+	defer RecoverAndLog(slog.New(NewHandler(io.Discard)), WithRepanic(true))
+	panic(errBoom)
+}
+
+func Test_panicError_Unwrap(t *testing.T) {
+	errBoom := errors.New("boom")
+	if err := (&panicError{value: errBoom}); !errors.Is(err, errBoom) {
+		t.Errorf("errors.Is(%v, %v) = false, want true", err, errBoom)
+	}
+	if err := (&panicError{value: "boom"}).Unwrap(); err != nil {
+		t.Errorf("Unwrap() = %v, want nil", err)
+	}
+}
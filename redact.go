@@ -0,0 +1,118 @@
+package sloggcp
+
+import (
+	"maps"
+	"slices"
+)
+
+// RedactedValue replaces the values of keys configured by [WithRedactKeys].
+const RedactedValue = "[REDACTED]"
+
+// redact replaces the values of redacted keys in out and all nested objects.
+// Nested objects are copied before modification, as they may be owned by the caller.
+func (c *config) redact(out map[string]any) {
+	if len(c.redactKeys) == 0 {
+		return
+	}
+	for k, v := range out {
+		if _, ok := c.redactKeys[k]; ok {
+			out[k] = RedactedValue
+		} else if v, changed := c.redactValue(v); changed {
+			out[k] = v
+		}
+	}
+}
+
+// redactValue returns a copy of v with redacted values,
+// or v itself and false if nothing needs to be redacted.
+// Objects, label maps, slices and the entries of joined errors are redacted.
+func (c *config) redactValue(v any) (any, bool) {
+	switch tv := v.(type) {
+	case map[string]any:
+		return c.redactNested(tv)
+	case map[string]string:
+		return c.redactLabels(tv)
+	case []any:
+		var out []any
+		for i, e := range tv {
+			e, changed := c.redactValue(e)
+			if !changed {
+				continue
+			}
+			if out == nil {
+				out = slices.Clone(tv)
+			}
+			out[i] = e
+		}
+		if out == nil {
+			return v, false
+		}
+		return out, true
+	case []joinedError:
+		return c.redactJoinedErrors(tv)
+	}
+	return v, false
+}
+
+// redactNested returns a copy of m with redacted values,
+// or m itself and false if nothing needs to be redacted.
+func (c *config) redactNested(m map[string]any) (map[string]any, bool) {
+	var out map[string]any
+	for k, v := range m {
+		value := v
+		if _, ok := c.redactKeys[k]; ok {
+			value = RedactedValue
+		} else if nested, changed := c.redactValue(v); changed {
+			value = nested
+		} else {
+			continue
+		}
+		if out == nil {
+			out = maps.Clone(m)
+		}
+		out[k] = value
+	}
+	if out == nil {
+		return m, false
+	}
+	return out, true
+}
+
+// redactLabels returns a copy of labels with redacted values,
+// or labels itself and false if nothing needs to be redacted.
+func (c *config) redactLabels(labels map[string]string) (map[string]string, bool) {
+	var out map[string]string
+	for k := range labels {
+		if _, ok := c.redactKeys[k]; !ok {
+			continue
+		}
+		if out == nil {
+			out = maps.Clone(labels)
+		}
+		out[k] = RedactedValue
+	}
+	if out == nil {
+		return labels, false
+	}
+	return out, true
+}
+
+// redactJoinedErrors returns a copy of errs with the redacted fields of each entry,
+// or errs itself and false if nothing needs to be redacted.
+func (c *config) redactJoinedErrors(errs []joinedError) ([]joinedError, bool) {
+	_, message := c.redactKeys["message"]
+	_, stackTrace := c.redactKeys["stackTrace"]
+	if len(errs) == 0 || !message && !stackTrace {
+		return errs, false
+	}
+	out := slices.Clone(errs)
+	for i := range out {
+		if message {
+			out[i].Message = RedactedValue
+		}
+		if stackTrace && out[i].StackTrace != "" {
+			out[i].StackTrace = RedactedValue
+		}
+	}
+	return out, true
+}
@@ -0,0 +1,205 @@
+package sloggcp
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"reflect"
+	"testing"
+)
+
+type mockCredentials struct{}
+
+func (mockCredentials) LogValue() slog.Value {
+	return slog.GroupValue(
+		slog.String("user", "alice"),
+		slog.String("password", "secret"),
+	)
+}
+
+type mockCredentialsError struct {
+	mockCredentials
+}
+
+func (mockCredentialsError) Error() string {
+	return "login failed"
+}
+
+func TestWithRedactKeys(t *testing.T) {
+	tests := []struct {
+		name string
+		log  func(*slog.Logger)
+		want map[string]any
+	}{
+		{
+			name: "top-level",
+			log: func(l *slog.Logger) {
+				l.Info("test message", "password", "secret", "user", "alice")
+			},
+			want: map[string]any{
+				"password": RedactedValue,
+				"user":     "alice",
+			},
+		},
+		{
+			name: "nested group",
+			log: func(l *slog.Logger) {
+				l.Info("test message", slog.Group("request",
+					slog.Group("auth", slog.String("password", "secret")),
+					slog.String("path", "/login"),
+				))
+			},
+			want: map[string]any{
+				"request": map[string]any{
+					"auth": map[string]any{"password": RedactedValue},
+					"path": "/login",
+				},
+			},
+		},
+		{
+			name: "WithGroup",
+			log: func(l *slog.Logger) {
+				l.WithGroup("request").Info("test message", "authorization", "Bearer token")
+			},
+			want: map[string]any{
+				"request": map[string]any{"authorization": RedactedValue},
+			},
+		},
+		{
+			name: "LogValuer",
+			log: func(l *slog.Logger) {
+				l.Info("test message", "credentials", mockCredentials{})
+			},
+			want: map[string]any{
+				"credentials": map[string]any{"user": "alice", "password": RedactedValue},
+			},
+		},
+		{
+			name: "error value",
+			log: func(l *slog.Logger) {
+				l.Error("test message", "error", mockCredentialsError{})
+			},
+			want: map[string]any{
+				"error": map[string]any{"user": "alice", "password": RedactedValue},
+			},
+		},
+		{
+			name: "labels",
+			log: func(l *slog.Logger) {
+				l.Info("test message", Labels("password", "hunter2", "user", "alice"))
+			},
+			want: map[string]any{
+				LabelsKey: map[string]any{"password": RedactedValue, "user": "alice"},
+			},
+		},
+		{
+			name: "slice",
+			log: func(l *slog.Logger) {
+				l.Info("test message", "users", []any{
+					map[string]any{"user": "alice", "password": "secret"},
+					"bob",
+				})
+			},
+			want: map[string]any{
+				"users": []any{
+					map[string]any{"user": "alice", "password": RedactedValue},
+					"bob",
+				},
+			},
+		},
+		{
+			name: "slice WithAttrs",
+			log: func(l *slog.Logger) {
+				l.With("users", []any{map[string]any{"password": "secret"}}).Info("test message")
+			},
+			want: map[string]any{
+				"users": []any{map[string]any{"password": RedactedValue}},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			tt.log(slog.New(NewHandler(&buf, WithRedactKeys("password", "authorization"))))
+
+			var got map[string]any
+			if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+				t.Fatalf("Failed to decode log output: %v", err)
+			}
+			for key, want := range tt.want {
+				if !reflect.DeepEqual(got[key], want) {
+					t.Errorf("%s = %v, want %v", key, got[key], want)
+				}
+			}
+		})
+	}
+}
+
+func Test_config_redactNested(t *testing.T) {
+	c := newConfig([]Option{WithRedactKeys("password")})
+	m := map[string]any{
+		"auth": map[string]any{"password": "secret"},
+		"path": "/login",
+	}
+	got, changed := c.redactNested(m)
+	if !changed {
+		t.Fatal("redactNested() changed = false, want true")
+	}
+	want := map[string]any{
+		"auth": map[string]any{"password": RedactedValue},
+		"path": "/login",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("redactNested() = %v, want %v", got, want)
+	}
+	if m["auth"].(map[string]any)["password"] != "secret" {
+		t.Errorf("redactNested() modified the original map: %v", m)
+	}
+	if _, changed := c.redactNested(map[string]any{"path": "/login"}); changed {
+		t.Error("redactNested() changed = true, want false")
+	}
+}
+
+func TestWithRedactKeys_joinedErrors(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(NewHandler(&buf, WithRedactKeys("message")))
+	logger.Error("test message", "error", errors.Join(errors.New("password hunter2"), errors.New("second")))
+
+	var got map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+		t.Fatalf("Failed to decode log output: %v", err)
+	}
+	want := []any{
+		map[string]any{"message": RedactedValue},
+		map[string]any{"message": RedactedValue},
+	}
+	if !reflect.DeepEqual(got["error"], want) {
+		t.Errorf("error = %v, want %v", got["error"], want)
+	}
+}
+
+func Test_config_redactValue(t *testing.T) {
+	c := newConfig([]Option{WithRedactKeys("password")})
+	labels := map[string]string{"password": "hunter2", "user": "alice"}
+	got, changed := c.redactValue(labels)
+	if !changed {
+		t.Fatal("redactValue() changed = false, want true")
+	}
+	if want := map[string]string{"password": RedactedValue, "user": "alice"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("redactValue() = %v, want %v", got, want)
+	}
+	if labels["password"] != "hunter2" {
+		t.Errorf("redactValue() modified the original labels: %v", labels)
+	}
+	slice := []any{map[string]any{"password": "secret"}}
+	if _, changed := c.redactValue(slice); !changed {
+		t.Error("redactValue() changed = false, want true")
+	}
+	if slice[0].(map[string]any)["password"] != "secret" {
+		t.Errorf("redactValue() modified the original slice: %v", slice)
+	}
+	if _, changed := c.redactValue([]any{"alice"}); changed {
+		t.Error("redactValue() changed = true, want false")
+	}
+}
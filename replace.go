@@ -1,51 +1,210 @@
 package sloggcp
 
-import "log/slog"
+import (
+	"log/slog"
+	"slices"
+	"sync/atomic"
+)
 
 // ReplaceAttr replaces slog default attributes with GCP compatible ones
 // https://cloud.google.com/logging/docs/structured-logging
 // https://cloud.google.com/logging/docs/agent/logging/configuration#special-fields
+//
+// Levels are mapped to severities the same way as [NewErrorReportingHandler] does,
+// including the GCP specific levels such as [LevelNotice] and [LevelCritical].
 func ReplaceAttr(groups []string, a slog.Attr) slog.Attr {
-	// only handle top-level attributes
+	return defaultConfig.replaceAttr(groups, a)
+}
+
+// defaultConfig is used by [ReplaceAttr].
+var defaultConfig = newConfig(nil)
+
+// NewReplaceAttr returns a ReplaceAttr function like [ReplaceAttr],
+// configured by options.
+// Only options which affect the replaced attributes are relevant,
+// such as [WithTimestamp], [WithSourceTrimPrefix] and [WithSeverityMapper].
+func NewReplaceAttr(options ...Option) func(groups []string, a slog.Attr) slog.Attr {
+	return newConfig(options).replaceAttr
+}
+
+// NewErrorReplaceAttr returns a ReplaceAttr function like [NewReplaceAttr],
+// which also promotes records with a top-level error attribute to Error Reporting.
+// Unlike the handler, only values of type [error] are promoted, not strings.
+// The error attribute is replaced by an inline group with the [ErrorReportTypeKey] field,
+// the error value and, if the error provides a stack trace, the [StackTraceKey] field.
+// The report location of a [ReportLocationError] and the [WithServiceContext] are added as well.
+// Options such as [WithErrorKeys], [WithErrorReportType] and [WithMaxStackSize] apply.
+//
+// A ReplaceAttr function sees one attribute at a time, without the record,
+// so the promotion is best-effort:
+//   - The message is replaced before the error attribute is seen,
+//     so it is kept as is instead of being replaced by the error details.
+//   - A [WithErrorReportMinLevel] is checked against the last level the function has seen.
+//     Records are handled concurrently, so the level may belong to another record.
+//   - Error attributes of [slog.Logger.With] are replaced when the logger is created,
+//     so they are promoted for every record, regardless of the level.
+//   - Errors in groups are not promoted.
+//   - The stack attributes of [WithStackKeys] are folded into the message of error records,
+//     by repeating the [MessageKey] field with the stack appended to the last message seen.
+//     Decoders such as [encoding/json] keep the last of duplicate keys.
+//     Records below [LevelError] keep their stack attribute as is.
+//
+// Use [NewHandler] for reliable error reports.
+func NewErrorReplaceAttr(options ...Option) func(groups []string, a slog.Attr) slog.Attr {
+	r := &errorReplacer{config: newConfig(options)}
+	return r.replaceAttr
+}
+
+// errorReplacer holds the state of [NewErrorReplaceAttr].
+type errorReplacer struct {
+	config  *config
+	level   atomic.Int64           // last level seen
+	message atomic.Pointer[string] // last message seen
+}
+
+func (r *errorReplacer) replaceAttr(groups []string, a slog.Attr) slog.Attr {
+	if len(groups) > 0 {
+		return r.config.replaceAttr(groups, a)
+	}
+	switch a.Key {
+	case slog.LevelKey:
+		if level, ok := a.Value.Any().(slog.Level); ok {
+			r.level.Store(int64(level))
+		}
+	case slog.MessageKey:
+		if len(r.config.stackKeys) > 0 {
+			msg := a.Value.String()
+			r.message.Store(&msg)
+		}
+	}
+	if slices.Contains(r.config.stackKeys, a.Key) {
+		return r.foldStack(a)
+	}
+	if r.config.errorPriority(a) >= 0 && r.config.reportsError(slog.Level(r.level.Load())) {
+		if err, ok := errorAttrValue(a).(error); ok && !isNil(err) {
+			return r.config.errorReportAttr(a)
+		}
+	}
+	return r.config.replaceAttr(groups, a)
+}
+
+// foldStack returns the [MessageKey] attribute with the stack attribute a appended to the last message seen,
+// if the last level seen is an error level, or a itself otherwise.
+func (r *errorReplacer) foldStack(a slog.Attr) slog.Attr {
+	level := slog.Level(r.level.Load())
+	if level < LevelError || !r.config.reportsError(level) {
+		return a
+	}
+	stack := a.Value.Resolve().String()
+	if msg := r.message.Load(); msg != nil && *msg != "" {
+		stack = *msg + "\n\n" + stack
+	}
+	return slog.String(MessageKey, truncateStack(stack, r.config.maxStackSize))
+}
+
+// errorReportAttr returns an inline group with the error report fields of the error attribute a.
+func (c *config) errorReportAttr(a slog.Attr) slog.Attr {
+	value := errorAttrValue(a)
+	errMsg, reportLocation := assertErrorValue(value)
+	attrs := make([]slog.Attr, 0, 5)
+	attrs = append(attrs, slog.String(ErrorReportTypeKey, c.errorReportType))
+	if hasStackTrace(value) {
+		attrs = append(attrs, slog.String(StackTraceKey, truncateStack(errMsg, c.maxStackSize)))
+	}
+	if reportLocation.IsValid() {
+		attrs = append(attrs, slog.Any(ReportLocationKey, reportLocation))
+	}
+	if c.serviceContext != nil {
+		attrs = append(attrs, slog.Any(ServiceContextKey, c.serviceContext))
+	}
+	// The structured error value is no error, so it is not promoted again
+	// when the handler passes the members of the group to the ReplaceAttr function.
+	attrs = append(attrs, slog.Any(a.Key, errorValue(value)))
+	return slog.Attr{Value: slog.GroupValue(attrs...)}
+}
+
+// ReplaceAttrChain returns a ReplaceAttr function which calls fns in order,
+// passing the result of each function to the next.
+// If a function returns the zero [slog.Attr], the attribute is dropped
+// and the remaining functions are not called.
+// Nil functions are skipped.
+func ReplaceAttrChain(fns ...func(groups []string, a slog.Attr) slog.Attr) func(groups []string, a slog.Attr) slog.Attr {
+	return func(groups []string, a slog.Attr) slog.Attr {
+		for _, fn := range fns {
+			if fn == nil {
+				continue
+			}
+			if a = fn(groups, a); a.Equal(slog.Attr{}) {
+				return a
+			}
+		}
+		return a
+	}
+}
+
+// ReplaceAttrWith returns a ReplaceAttr function which calls [ReplaceAttr] first,
+// followed by next, as composed by [ReplaceAttrChain].
+// Functions in next receive the GCP keys, such as [SeverityKey] instead of [slog.LevelKey].
+func ReplaceAttrWith(next ...func(groups []string, a slog.Attr) slog.Attr) func(groups []string, a slog.Attr) slog.Attr {
+	return ReplaceAttrChain(append([]func([]string, slog.Attr) slog.Attr{ReplaceAttr}, next...)...)
+}
+
+func (c *config) replaceAttr(groups []string, a slog.Attr) slog.Attr {
+	// only handle top-level attributes, and the members of top-level labels
 	if len(groups) > 0 {
+		if len(groups) == 1 && groups[0] == LabelsKey {
+			return slog.String(a.Key, c.labelValue(a))
+		}
 		return a
 	}
 	switch a.Key {
 	case slog.LevelKey:
-		return replaceLevelAttr(a)
+		return c.replaceLevelAttr(a)
 	case slog.SourceKey:
 		a.Key = SourceLocationKey
+		if src, ok := a.Value.Any().(*slog.Source); ok {
+			if gs := c.source(src); !gs.isEmpty() {
+				a.Value = slog.AnyValue(gs)
+			} else {
+				return slog.Attr{}
+			}
+		}
 	case slog.MessageKey:
 		a.Key = MessageKey
 	case slog.TimeKey:
-		// no replacement needed
+		if (c.timestamp != timestampDefault || c.timeKeyName != "" || c.utc) && a.Value.Kind() == slog.KindTime {
+			return slog.Any(c.timeKey(), c.timeValue(a.Value.Time()))
+		}
+	default:
+		if slices.Contains(c.stackKeys, a.Key) {
+			a.Key = StackTraceKey
+		}
 	}
 	return a
 }
 
-var (
-	severityDebug   = slog.String(SeverityKey, DebugSeverity)
-	severityInfo    = slog.String(SeverityKey, InfoSeverity)
-	severityWarn    = slog.String(SeverityKey, WarningSeverity)
-	severityError   = slog.String(SeverityKey, ErrorSeverity)
-	severityDefault = slog.String(SeverityKey, DefaultSeverity)
-)
+// StackTraceKey is a payload field which Error Reporting scans for stack traces,
+// besides the "message" field. See https://cloud.google.com/error-reporting/docs/formatting-error-messages.
+const StackTraceKey = "stack_trace"
 
-func replaceLevelAttr(a slog.Attr) slog.Attr {
-	logLevel, ok := a.Value.Any().(slog.Level)
-	if !ok {
-		return severityDefault
-	}
-	switch logLevel {
-	case slog.LevelDebug:
-		return severityDebug
-	case slog.LevelInfo:
-		return severityInfo
-	case slog.LevelWarn:
-		return severityWarn
-	case slog.LevelError:
-		return severityError
-	default:
-		return severityDefault
+// WithStackKeys makes [NewReplaceAttr] rename top-level attributes with one of keys,
+// such as "stack" or "stacktrace", to [StackTraceKey], so that Error Reporting finds the stack trace.
+//
+// The function of [NewReplaceAttr] is stateless, so the stack attribute is renamed for all records,
+// and the message is kept as is.
+// The stateful function of [NewErrorReplaceAttr] folds the stack into the message of error records instead,
+// on a best-effort basis, as documented there.
+// The option has no effect on the handler, which embeds the stack trace of a [StackTraceError] in the message.
+func WithStackKeys(keys ...string) Option {
+	return func(c *config) {
+		c.stackKeys = slices.Clone(keys)
+	}
+}
+
+func (c *config) replaceLevelAttr(a slog.Attr) slog.Attr {
+	severity := DefaultSeverity
+	if logLevel, ok := a.Value.Any().(slog.Level); ok {
+		severity = c.severity(logLevel)
 	}
+	return slog.Any(SeverityKey, c.severityField(severity))
 }
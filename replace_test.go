@@ -2,9 +2,12 @@ package sloggcp
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
 	"log/slog"
 	"os"
+	"reflect"
 	"testing"
 	"time"
 )
@@ -86,11 +89,67 @@ func TestReplaceAttr(t *testing.T) {
 			want: slog.String("severity", "ERROR"),
 		},
 		{
-			name: "LevelKey Invalid level",
+			name: "LevelKey Notice",
+			args: args{
+				groups: []string{},
+				a:      slog.Any(slog.LevelKey, LevelNotice),
+			},
+			want: slog.String("severity", "NOTICE"),
+		},
+		{
+			name: "LevelKey Critical",
+			args: args{
+				groups: []string{},
+				a:      slog.Any(slog.LevelKey, LevelCritical),
+			},
+			want: slog.String("severity", "CRITICAL"),
+		},
+		{
+			name: "LevelKey Alert",
+			args: args{
+				groups: []string{},
+				a:      slog.Any(slog.LevelKey, LevelAlert),
+			},
+			want: slog.String("severity", "ALERT"),
+		},
+		{
+			name: "LevelKey Emergency",
+			args: args{
+				groups: []string{},
+				a:      slog.Any(slog.LevelKey, LevelEmergency),
+			},
+			want: slog.String("severity", "EMERGENCY"),
+		},
+		{
+			name: "LevelKey intermediate level",
 			args: args{
 				groups: []string{},
 				a:      slog.Any(slog.LevelKey, slog.Level(-1)),
 			},
+			want: slog.String("severity", "DEBUG"),
+		},
+		{
+			name: "LevelKey Info+1",
+			args: args{
+				groups: []string{},
+				a:      slog.Any(slog.LevelKey, slog.LevelInfo+1),
+			},
+			want: slog.String("severity", "INFO"),
+		},
+		{
+			name: "LevelKey Warn+1",
+			args: args{
+				groups: []string{},
+				a:      slog.Any(slog.LevelKey, slog.LevelWarn+1),
+			},
+			want: slog.String("severity", "WARNING"),
+		},
+		{
+			name: "LevelKey Invalid level",
+			args: args{
+				groups: []string{},
+				a:      slog.Any(slog.LevelKey, slog.Level(-10)),
+			},
 			want: slog.String("severity", "DEFAULT"),
 		},
 		{
@@ -107,7 +166,15 @@ func TestReplaceAttr(t *testing.T) {
 				groups: []string{},
 				a:      slog.Any(slog.SourceKey, &someSource),
 			},
-			want: slog.Any("logging.googleapis.com/sourceLocation", &someSource),
+			want: slog.Any("logging.googleapis.com/sourceLocation", (*gcpSource)(&someSource)),
+		},
+		{
+			name: "SourceKey empty",
+			args: args{
+				groups: []string{},
+				a:      slog.Any(slog.SourceKey, &slog.Source{}),
+			},
+			want: slog.Attr{},
 		},
 		{
 			name: "MessageKey",
@@ -148,7 +215,7 @@ func TestReplaceAttr_LogOutput(t *testing.T) {
 	logger := slog.New(slog.NewJSONHandler(&buf, &slog.HandlerOptions{
 		ReplaceAttr: ReplaceAttr,
 		AddSource:   true,
-		Level:       slog.LevelDebug,
+		Level:       slog.Level(-10),
 	}))
 	out := json.NewDecoder(&buf)
 
@@ -177,9 +244,29 @@ func TestReplaceAttr_LogOutput(t *testing.T) {
 			level:        slog.LevelError,
 			wantSeverity: ErrorSeverity,
 		},
+		{
+			name:         "Notice",
+			level:        LevelNotice,
+			wantSeverity: NoticeSeverity,
+		},
+		{
+			name:         "Critical",
+			level:        LevelCritical,
+			wantSeverity: CriticalSeverity,
+		},
+		{
+			name:         "Alert",
+			level:        LevelAlert,
+			wantSeverity: AlertSeverity,
+		},
+		{
+			name:         "Emergency",
+			level:        LevelEmergency,
+			wantSeverity: EmergencySeverity,
+		},
 		{
 			name:         "Default",
-			level:        slog.Level(-1),
+			level:        slog.Level(-10),
 			wantSeverity: DefaultSeverity,
 		},
 	}
@@ -207,3 +294,285 @@ func TestReplaceAttr_LogOutput(t *testing.T) {
 
 	}
 }
+
+func Test_replaceLevelAttr_SeverityFromLevel(t *testing.T) {
+	for level := LevelDebug - 10; level <= LevelEmergency+10; level++ {
+		got := defaultConfig.replaceLevelAttr(slog.Any(slog.LevelKey, level)).Value.String()
+		if want := SeverityFromLevel(level); got != want {
+			t.Errorf("level %d: replaceLevelAttr() = %v, SeverityFromLevel() = %v", level, got, want)
+		}
+	}
+}
+
+func TestWithSeverityMapper(t *testing.T) {
+	mapper := func(level slog.Level) string {
+		if level >= LevelError {
+			return "PAGE"
+		}
+		return "ROUTINE"
+	}
+	var handlerBuf, replaceBuf bytes.Buffer
+	handlers := map[string]struct {
+		h   slog.Handler
+		buf *bytes.Buffer
+	}{
+		"handler": {NewHandler(&handlerBuf, WithSeverityMapper(mapper)), &handlerBuf},
+		"ReplaceAttr": {slog.NewJSONHandler(&replaceBuf, &slog.HandlerOptions{
+			ReplaceAttr: NewReplaceAttr(WithSeverityMapper(mapper)),
+		}), &replaceBuf},
+	}
+	for name, tt := range handlers {
+		t.Run(name, func(t *testing.T) {
+			logger := slog.New(tt.h)
+			for _, want := range []struct {
+				level slog.Level
+				want  string
+			}{
+				{LevelInfo, "ROUTINE"},
+				{LevelCritical, "PAGE"},
+			} {
+				tt.buf.Reset()
+				logger.Log(context.Background(), want.level, "test message")
+				var got expectSchema
+				if err := json.Unmarshal(tt.buf.Bytes(), &got); err != nil {
+					t.Fatalf("Failed to decode log output: %v", err)
+				}
+				if got.Severity != want.want {
+					t.Errorf("level %v: severity = %q, want %q", want.level, got.Severity, want.want)
+				}
+			}
+		})
+	}
+}
+
+func TestReplaceAttrChain(t *testing.T) {
+	dropTime := func(groups []string, a slog.Attr) slog.Attr {
+		if len(groups) == 0 && a.Key == slog.TimeKey {
+			return slog.Attr{}
+		}
+		return a
+	}
+	var called bool
+	after := func(groups []string, a slog.Attr) slog.Attr {
+		if a.Key == slog.TimeKey {
+			called = true
+		}
+		return a
+	}
+
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&buf, &slog.HandlerOptions{
+		ReplaceAttr: ReplaceAttrChain(ReplaceAttr, nil, dropTime, after),
+	}))
+	logger.Warn("test message")
+
+	var got map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+		t.Fatalf("Failed to decode log output: %v", err)
+	}
+	if _, ok := got[slog.TimeKey]; ok {
+		t.Errorf("time key not dropped: %v", got)
+	}
+	if got[SeverityKey] != WarningSeverity {
+		t.Errorf("severity = %v, want %v", got[SeverityKey], WarningSeverity)
+	}
+	if called {
+		t.Error("function after dropped attribute was called")
+	}
+}
+
+func TestReplaceAttrWith(t *testing.T) {
+	redact := func(groups []string, a slog.Attr) slog.Attr {
+		if a.Key == "password" {
+			return slog.Attr{}
+		}
+		return a
+	}
+	var buf bytes.Buffer
+	logger := slog.New(NewHandler(&buf, WithHandlerOptions(&slog.HandlerOptions{
+		ReplaceAttr: ReplaceAttrWith(redact),
+	})))
+	logger.Info("test message", "password", "secret", "user", "alice")
+
+	var got map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+		t.Fatalf("Failed to decode log output: %v", err)
+	}
+	if _, ok := got["password"]; ok {
+		t.Errorf("password not dropped: %v", got)
+	}
+	if _, ok := got[""]; ok {
+		t.Errorf("empty key in output: %v", got)
+	}
+	if got["user"] != "alice" {
+		t.Errorf("user = %v, want %v", got["user"], "alice")
+	}
+}
+
+func TestWithStackKeys(t *testing.T) {
+	const stack = "goroutine 1 [running]:\nmain.main()\n\t/app/main.go:10 +0x1d"
+	replace := NewReplaceAttr(WithStackKeys("stack", "stacktrace"))
+	tests := []struct {
+		name   string
+		groups []string
+		a      slog.Attr
+		want   slog.Attr
+	}{
+		{
+			name: "stack",
+			a:    slog.String("stack", stack),
+			want: slog.String(StackTraceKey, stack),
+		},
+		{
+			name: "stacktrace",
+			a:    slog.String("stacktrace", stack),
+			want: slog.String(StackTraceKey, stack),
+		},
+		{
+			name: "other key",
+			a:    slog.String("trace", stack),
+			want: slog.String("trace", stack),
+		},
+		{
+			name:   "nested",
+			groups: []string{"group"},
+			a:      slog.String("stack", stack),
+			want:   slog.String("stack", stack),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := replace(tt.groups, tt.a); !got.Equal(tt.want) {
+				t.Errorf("replace() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	var buf bytes.Buffer
+	slog.New(slog.NewJSONHandler(&buf, &slog.HandlerOptions{ReplaceAttr: replace})).
+		Error("panic", "stack", stack)
+	var got map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+		t.Fatalf("Failed to decode log output: %v", err)
+	}
+	if got[StackTraceKey] != stack || got[MessageKey] != "panic" {
+		t.Errorf("log output = %v, want %s and message", got, StackTraceKey)
+	}
+
+	// the keys must not be shared with the caller
+	keys := []string{"stack"}
+	replace = NewReplaceAttr(WithStackKeys(keys...))
+	keys[0] = "other"
+	if got := replace(nil, slog.String("stack", stack)); got.Key != StackTraceKey {
+		t.Errorf("replace() key = %q after modifying the keys, want %q", got.Key, StackTraceKey)
+	}
+	if _, ok := got["stack"]; ok {
+		t.Errorf("stack key not renamed: %v", got)
+	}
+}
+
+func TestNewErrorReplaceAttr(t *testing.T) {
+	tests := []struct {
+		name    string
+		options []Option
+		log     func(*slog.Logger)
+		want    map[string]any
+	}{
+		{
+			name: "error",
+			log:  func(l *slog.Logger) { l.Error("test message", "error", errors.New("boom")) },
+			want: map[string]any{
+				SeverityKey:        ErrorSeverity,
+				MessageKey:         "test message",
+				ErrorReportTypeKey: ErrorReportTypeValue,
+				ErrorKey:           "boom",
+			},
+		},
+		{
+			name:    "stack trace and service context",
+			options: []Option{WithServiceContext("svc", "v1")},
+			log:     func(l *slog.Logger) { l.Error("test message", Err(mockStackTraceError{})) },
+			want: map[string]any{
+				SeverityKey:        ErrorSeverity,
+				MessageKey:         "test message",
+				ErrorReportTypeKey: ErrorReportTypeValue,
+				StackTraceKey:      "stack",
+				ServiceContextKey:  map[string]any{"service": "svc", "version": "v1"},
+				ErrorKey:           "mockStackTraceError",
+			},
+		},
+		{
+			name:    "below error report level",
+			options: []Option{WithErrorReportMinLevel(slog.LevelError)},
+			log:     func(l *slog.Logger) { l.Warn("test message", "error", errors.New("boom")) },
+			want: map[string]any{
+				SeverityKey: WarningSeverity,
+				MessageKey:  "test message",
+				ErrorKey:    "boom",
+			},
+		},
+		{
+			name: "string error",
+			log:  func(l *slog.Logger) { l.Error("test message", "error", "boom") },
+			want: map[string]any{
+				SeverityKey: ErrorSeverity,
+				MessageKey:  "test message",
+				ErrorKey:    "boom",
+			},
+		},
+		{
+			name:    "stack folded into message",
+			options: []Option{WithStackKeys("stack")},
+			log:     func(l *slog.Logger) { l.Error("test message", "stack", "goroutine 1 [running]:") },
+			want: map[string]any{
+				SeverityKey: ErrorSeverity,
+				MessageKey:  "test message\n\ngoroutine 1 [running]:",
+			},
+		},
+		{
+			name:    "stack of non-error record",
+			options: []Option{WithStackKeys("stack")},
+			log:     func(l *slog.Logger) { l.Warn("test message", "stack", "goroutine 1 [running]:") },
+			want: map[string]any{
+				SeverityKey: WarningSeverity,
+				MessageKey:  "test message",
+				"stack":     "goroutine 1 [running]:",
+			},
+		},
+		{
+			name:    "stack below error report level",
+			options: []Option{WithStackKeys("stack"), WithErrorReportMinLevel(LevelCritical)},
+			log:     func(l *slog.Logger) { l.Error("test message", "stack", "goroutine 1 [running]:") },
+			want: map[string]any{
+				SeverityKey: ErrorSeverity,
+				MessageKey:  "test message",
+				"stack":     "goroutine 1 [running]:",
+			},
+		},
+		{
+			name: "grouped error",
+			log:  func(l *slog.Logger) { l.Error("test message", slog.Group("g", "error", errors.New("boom"))) },
+			want: map[string]any{
+				SeverityKey: ErrorSeverity,
+				MessageKey:  "test message",
+				"g":         map[string]any{ErrorKey: "boom"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			tt.log(slog.New(slog.NewJSONHandler(&buf, &slog.HandlerOptions{
+				ReplaceAttr: NewErrorReplaceAttr(tt.options...),
+			})))
+			var got map[string]any
+			if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+				t.Fatalf("Failed to decode log output: %v", err)
+			}
+			delete(got, TimeKey)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("output =\n%v\nwant\n%v", got, tt.want)
+			}
+		})
+	}
+}
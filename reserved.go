@@ -0,0 +1,52 @@
+package sloggcp
+
+import "log/slog"
+
+// ReservedKeyPolicy determines how the handler treats top-level attributes
+// with the key of a field emitted by the handler itself,
+// such as [SeverityKey], [MessageKey] and [TimeKey].
+type ReservedKeyPolicy int
+
+const (
+	// ReservedKeyRename prefixes the key with [ReservedKeyPrefix]. This is the default.
+	ReservedKeyRename ReservedKeyPolicy = iota
+	// ReservedKeyDrop drops the attribute.
+	ReservedKeyDrop
+	// ReservedKeyAllow keeps the attribute, which overwrites the field emitted by the handler.
+	ReservedKeyAllow
+)
+
+// ReservedKeyPrefix is prepended to reserved keys by [ReservedKeyRename].
+const ReservedKeyPrefix = "_"
+
+// reservedKeys are the keys of fields emitted by the handler itself.
+var reservedKeys = map[string]bool{
+	SeverityKey:        true,
+	MessageKey:         true,
+	TimeKey:            true,
+	TimestampKey:       true,
+	ErrorReportTypeKey: true,
+	EventTimeKey:       true,
+}
+
+// checkReserved applies the [ReservedKeyPolicy] to the top-level attribute a.
+// The key set by [WithTimeKey] is reserved as well.
+// The zero [slog.Attr] is returned if a is dropped.
+// The attributes of [Severity] and [Message] are always kept.
+func (c *config) checkReserved(a slog.Attr) slog.Attr {
+	if a, ok := c.checkOverride(a); ok {
+		return a
+	}
+	if !reservedKeys[a.Key] && a.Key != c.timeKey() {
+		return a
+	}
+	switch c.reservedKeyPolicy {
+	case ReservedKeyDrop:
+		return slog.Attr{}
+	case ReservedKeyAllow:
+		return a
+	default:
+		a.Key = ReservedKeyPrefix + a.Key
+		return a
+	}
+}
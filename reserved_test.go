@@ -0,0 +1,122 @@
+package sloggcp
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"reflect"
+	"testing"
+)
+
+func TestWithReservedKeyPolicy(t *testing.T) {
+	tests := []struct {
+		name    string
+		options []Option
+		want    map[string]any
+	}{
+		{
+			name: "default",
+			want: map[string]any{
+				MessageKey:                      "record message",
+				SeverityKey:                     InfoSeverity,
+				ReservedKeyPrefix + MessageKey:  "user message",
+				ReservedKeyPrefix + SeverityKey: "user severity",
+				"group":                         map[string]any{MessageKey: "grouped message"},
+			},
+		},
+		{
+			name:    "rename",
+			options: []Option{WithReservedKeyPolicy(ReservedKeyRename)},
+			want: map[string]any{
+				MessageKey:                      "record message",
+				SeverityKey:                     InfoSeverity,
+				ReservedKeyPrefix + MessageKey:  "user message",
+				ReservedKeyPrefix + SeverityKey: "user severity",
+				"group":                         map[string]any{MessageKey: "grouped message"},
+			},
+		},
+		{
+			name:    "drop",
+			options: []Option{WithReservedKeyPolicy(ReservedKeyDrop)},
+			want: map[string]any{
+				MessageKey:  "record message",
+				SeverityKey: InfoSeverity,
+				"group":     map[string]any{MessageKey: "grouped message"},
+			},
+		},
+		{
+			name:    "allow",
+			options: []Option{WithReservedKeyPolicy(ReservedKeyAllow)},
+			want: map[string]any{
+				MessageKey:  "user message",
+				SeverityKey: "user severity",
+				"group":     map[string]any{MessageKey: "grouped message"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			logger := slog.New(NewHandler(&buf, tt.options...))
+			logger.With(SeverityKey, "user severity").Info("record message",
+				MessageKey, "user message",
+				slog.Group("group", MessageKey, "grouped message"),
+			)
+
+			var got map[string]any
+			if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+				t.Fatalf("Failed to decode log output: %v", err)
+			}
+			delete(got, TimeKey)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("log output = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWithReservedKeyPolicy_timeKey(t *testing.T) {
+	tests := []struct {
+		name    string
+		options []Option
+		want    map[string]any
+	}{
+		{
+			name: "rename",
+			want: map[string]any{ReservedKeyPrefix + "ts": "user"},
+		},
+		{
+			name:    "drop",
+			options: []Option{WithReservedKeyPolicy(ReservedKeyDrop)},
+			want:    map[string]any{},
+		},
+		{
+			name:    "allow",
+			options: []Option{WithReservedKeyPolicy(ReservedKeyAllow)},
+			want:    map[string]any{"ts": "user"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			logger := slog.New(NewHandler(&buf, append(tt.options, WithTimeKey("ts"))...))
+			logger.Info("x", "ts", "user")
+
+			var got map[string]any
+			if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+				t.Fatalf("Failed to decode log output: %v", err)
+			}
+			if _, ok := tt.want["ts"]; !ok {
+				if _, ok := got["ts"].(string); !ok {
+					t.Errorf("ts = %v, want the record time", got["ts"])
+				}
+				delete(got, "ts")
+			}
+			delete(got, MessageKey)
+			delete(got, SeverityKey)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("log output = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
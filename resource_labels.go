@@ -0,0 +1,54 @@
+package sloggcp
+
+import (
+	"maps"
+	"os"
+)
+
+// WithResourceLabels adds labels to the [LabelsKey] object of every record.
+// Structured logs can't set the labels of the monitored resource, which are added by the logging agent,
+// so resource labels which the agent doesn't add are emitted as entry labels instead.
+// Multiple calls merge the labels. The map is copied.
+// Labels set by attributes override the resource labels.
+func WithResourceLabels(labels map[string]string) Option {
+	return func(c *config) {
+		if len(labels) == 0 {
+			return
+		}
+		if c.resourceLabels == nil {
+			c.resourceLabels = make(map[string]string, len(labels))
+		}
+		maps.Copy(c.resourceLabels, labels)
+	}
+}
+
+// Labels set by [WithCloudRunLabels], named after the labels of the cloud_run_revision resource.
+const (
+	ServiceNameLabel       = "service_name"
+	RevisionNameLabel      = "revision_name"
+	ConfigurationNameLabel = "configuration_name"
+)
+
+// cloudRunEnv maps the environment variables of Cloud Run to the labels of [WithCloudRunLabels].
+var cloudRunEnv = map[string]string{
+	"K_SERVICE":       ServiceNameLabel,
+	"K_REVISION":      RevisionNameLabel,
+	"K_CONFIGURATION": ConfigurationNameLabel,
+}
+
+// WithCloudRunLabels adds the [ServiceNameLabel], [RevisionNameLabel] and [ConfigurationNameLabel] labels
+// to every record, as [WithResourceLabels] does.
+// The values are read from the K_SERVICE, K_REVISION and K_CONFIGURATION environment variables
+// when the handler is created. Unset or empty variables are omitted,
+// so the option has no effect outside of Cloud Run.
+func WithCloudRunLabels() Option {
+	return func(c *config) {
+		labels := make(map[string]string, len(cloudRunEnv))
+		for env, label := range cloudRunEnv {
+			if v := os.Getenv(env); v != "" {
+				labels[label] = v
+			}
+		}
+		WithResourceLabels(labels)(c)
+	}
+}
@@ -0,0 +1,72 @@
+package sloggcp
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"reflect"
+	"testing"
+)
+
+func TestWithResourceLabels(t *testing.T) {
+	var buf bytes.Buffer
+	labels := map[string]string{"zone": "europe-west1-b", "env": "prod"}
+	logger := slog.New(NewHandler(&buf,
+		WithResourceLabels(labels),
+		WithResourceLabels(map[string]string{"instance": "abc"}),
+	))
+	labels["zone"] = "modified"
+	logger.Info("test message", Labels("env", "dev"))
+
+	var got struct {
+		Labels map[string]string `json:"logging.googleapis.com/labels"`
+	}
+	if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+		t.Fatalf("Failed to decode log output: %v", err)
+	}
+	want := map[string]string{"zone": "europe-west1-b", "env": "dev", "instance": "abc"}
+	if !reflect.DeepEqual(got.Labels, want) {
+		t.Errorf("labels = %v, want %v", got.Labels, want)
+	}
+}
+
+func TestWithCloudRunLabels(t *testing.T) {
+	tests := []struct {
+		name string
+		env  map[string]string
+		want map[string]string
+	}{
+		{
+			name: "set",
+			env:  map[string]string{"K_SERVICE": "svc", "K_REVISION": "svc-00001-abc", "K_CONFIGURATION": "svc"},
+			want: map[string]string{ServiceNameLabel: "svc", RevisionNameLabel: "svc-00001-abc", ConfigurationNameLabel: "svc"},
+		},
+		{
+			name: "partially set",
+			env:  map[string]string{"K_SERVICE": "svc", "K_REVISION": "", "K_CONFIGURATION": ""},
+			want: map[string]string{ServiceNameLabel: "svc"},
+		},
+		{
+			name: "unset",
+			env:  map[string]string{"K_SERVICE": "", "K_REVISION": "", "K_CONFIGURATION": ""},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for k, v := range tt.env {
+				t.Setenv(k, v)
+			}
+			var buf bytes.Buffer
+			slog.New(NewHandler(&buf, WithCloudRunLabels())).Info("test message")
+			var got struct {
+				Labels map[string]string `json:"logging.googleapis.com/labels"`
+			}
+			if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+				t.Fatalf("Failed to decode log output: %v", err)
+			}
+			if !reflect.DeepEqual(got.Labels, tt.want) {
+				t.Errorf("labels = %v, want %v", got.Labels, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,68 @@
+package sloggcp
+
+import (
+	"context"
+	"log/slog"
+	"sync/atomic"
+)
+
+// sampler keeps 1 out of rate records.
+type sampler struct {
+	rate  uint64
+	count atomic.Uint64
+}
+
+func (s *sampler) keep() bool {
+	return (s.count.Add(1)-1)%s.rate == 0
+}
+
+// WithSampling keeps only 1 out of N records at the levels of perLevel,
+// where N is the value for the level. Other records are dropped without being written.
+// Records at [LevelWarning] and above, records with an error attribute and records with [Force] are never dropped.
+// Rates below 2 disable sampling for the level.
+//
+// Sampling is shared between the handler and its derivatives and is safe for concurrent use.
+func WithSampling(perLevel map[slog.Level]int) Option {
+	return func(c *config) {
+		c.sampling = make(map[slog.Level]*sampler, len(perLevel))
+		for level, rate := range perLevel {
+			if rate > 1 && level < LevelWarning {
+				c.sampling[level] = &sampler{rate: uint64(rate)}
+			}
+		}
+	}
+}
+
+// sampled reports whether r is kept by the sampling of [WithSampling].
+func (h *handler) sampled(ctx context.Context, r *slog.Record) bool {
+	s, ok := h.config.sampling[r.Level]
+	if !ok || isForced(r) || h.hasErrorAttr(ctx, r) {
+		return true
+	}
+	return s.keep()
+}
+
+// hasErrorAttr reports whether r, the handler or ctx carry an error attribute.
+func (h *handler) hasErrorAttr(ctx context.Context, r *slog.Record) bool {
+	isError := func(a slog.Attr) bool {
+		return h.config.errorPriority(a) >= 0 && !isNil(a.Value.Any())
+	}
+	for _, a := range contextAttrs(ctx) {
+		if isError(a) {
+			return true
+		}
+	}
+	for _, goa := range h.goas {
+		for _, p := range goa.prepared {
+			if isError(p.Attr) {
+				return true
+			}
+		}
+	}
+	var found bool
+	r.Attrs(func(a slog.Attr) bool {
+		found = isError(a)
+		return !found
+	})
+	return found
+}
@@ -0,0 +1,72 @@
+package sloggcp
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"maps"
+	"testing"
+)
+
+func TestWithSampling(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(NewHandler(&buf,
+		WithMinLevel(slog.LevelDebug),
+		WithSampling(map[slog.Level]int{
+			slog.LevelInfo:  10,
+			slog.LevelError: 10, // ignored
+		}),
+	))
+	for range 100 {
+		logger.Info("info message")
+	}
+	for range 5 {
+		logger.Error("error message")
+	}
+	for range 3 {
+		logger.Info("info message with error", "error", errors.New("oops"))
+	}
+	for range 3 {
+		logger.Debug("debug message")
+	}
+
+	counts := make(map[string]int)
+	for line := range bytes.Lines(buf.Bytes()) {
+		var got expectSchema
+		if err := json.Unmarshal(line, &got); err != nil {
+			t.Fatalf("Failed to decode log output: %v", err)
+		}
+		counts[got.Severity]++
+	}
+	want := map[string]int{
+		InfoSeverity:  10 + 3,
+		ErrorSeverity: 5,
+		DebugSeverity: 3,
+	}
+	if !maps.Equal(counts, want) {
+		t.Errorf("records per severity = %v, want %v", counts, want)
+	}
+}
+
+func TestWithSampling_replaceAttr(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(NewHandler(&buf,
+		WithHandlerOptions(&slog.HandlerOptions{
+			ReplaceAttr: func(groups []string, a slog.Attr) slog.Attr {
+				if a.Key == ErrorKey {
+					return slog.Attr{}
+				}
+				return a
+			},
+		}),
+		WithSampling(map[slog.Level]int{slog.LevelInfo: 10}),
+	)).With(ErrorKey, errors.New("oops"))
+	for range 10 {
+		logger.Info("info message")
+	}
+	// the error attribute is removed by ReplaceAttr, so the records are sampled
+	if n := bytes.Count(buf.Bytes(), []byte("\n")); n != 1 {
+		t.Errorf("records = %d, want 1", n)
+	}
+}
@@ -2,12 +2,19 @@
 package sloggcp
 
 import (
+	"bytes"
 	"context"
 	"encoding"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"log/slog"
+	"maps"
+	"os"
+	"reflect"
+	"slices"
+	"strings"
 	"sync"
 	"time"
 )
@@ -18,6 +25,7 @@ const (
 	MessageKey        = "message"                               // [slog.MessageKey] replacement
 	SourceLocationKey = "logging.googleapis.com/sourceLocation" // [slog.SourceKey] replacement
 	TimeKey           = slog.TimeKey                            // time key (no replacement needed)
+	TimestampKey      = "timestamp"                             // alternative time key, see [WithTimestamp]
 )
 
 type Level = slog.Level
@@ -68,44 +76,192 @@ var DefaultOpts = slog.HandlerOptions{
 //   - Attributes with [fmt.Stringer] values are replaced by the result of their String() method.
 //   - All other attribute values are used as-is and handled according to [json.Marshal] rules.
 //
+// Attributes are processed in the order they were added to the logger and the record.
+// When a key occurs multiple times within the same group, the last value is used.
+// Top-level attributes with the key of a field emitted by the handler, such as "message",
+// are renamed by default, see [WithReservedKeyPolicy].
+//
 // When opts is nil, [DefaultOpts] is used.
 // If ReplaceAttr is set in opts, it is called before error reporting handling.
+// Additional GCP specific behavior can be configured through options.
+//
+// When the context passed to the logger carries a valid OpenTelemetry span context,
+// the [TraceKey], [SpanIDKey] and [TraceSampledKey] fields are added to the output.
+// The trace name is qualified with the project ID set by [WithProjectID].
+//
+// Attributes created by [Labels] are merged into the top-level [LabelsKey] object,
+// with all values coerced to strings, regardless of the group they are logged in.
+// Likewise, top-level attributes created by [ErrorUser] are merged into the [ErrorContextKey] object.
 //
-// When a record contains an attribute with key [ErrorKey],
+// When a record contains an attribute with key [ErrorKey], or a key set by [WithErrorKeys],
 // an error report is created according to GCP error reporting specifications.
+// Error attributes inside groups of [slog.Logger.WithGroup] are detected as well:
+// the error value stays in its group, while the error report attributes are set at the top level.
+// Top-level error attributes take precedence over grouped ones.
+// Values created by [Err] are recognized under any key and take precedence over the error keys.
+// If multiple error keys are present, the key listed first in [WithErrorKeys] is used.
+// If the same key is present multiple times, the last attribute is used.
 // The message attribute will then contain error details, as required by GCP error reporting.
-// The passed log message is ignored.
+// The passed log message is ignored, unless [WithPreserveMessage] is enabled.
+// The "eventTime" ([EventTimeKey]) attribute is set from the record time,
+// or the current time if the record time is zero.
 //
 // Certain attributes depend on the type of the error value.
+// Error values are unwrapped, and the innermost matching error of the chain is used.
 // The "message" ([MessageKey]) attribute value is determined in the following order:
 //  1. [StackTraceError] type: The stack trace output.
-//  2. [string] and [error] types: The error string.
+//  2. [error] types with a GRPCStatus method, as implemented by google.golang.org/grpc/status:
+//     The status message.
+//  3. [error] types with a github.com/pkg/errors style StackTrace method:
+//     The error string, followed by the rendered stack trace.
+//  4. [string] and [error] types: The error string.
+//
+// When enabled by [WithRecordStack], errors which are no [StackTraceError]
+// get a stack trace appended to the error string, starting at the log call site.
 //
 // The "reportLocation" ([ReportLocationKey]) attribute is added
-// if the error value implements [ReportLocationError].
+// if an error in the chain implements [ReportLocationError],
+// or derived from the log call site when enabled by [WithRecordLocation].
+// It is added at the top level, or inside the "context" ([ErrorContextKey])
+// object when [WithNestedReportContext] is enabled.
 //
 // The value associated with [ErrorKey] is determined in the following order:
 //  1. [slog.LogValuer] type: The result of its LogValue() method.
-//  2. [string] and [error] types: The error string.
-func NewErrorReportingHandler(w io.Writer, opts *slog.HandlerOptions) slog.Handler {
-	if opts == nil {
-		opts = &DefaultOpts
+//  2. [error] types with a GRPCStatus method: An object with the code, message and details of the status.
+//  3. Errors with an Unwrap() []error method, such as created by [errors.Join]:
+//     A list of objects with the message and, if available, the stack trace of each error.
+//  4. [string] and [error] types: The error string.
+//
+// Both orders are independent of each other. For example, a [slog.LogValuer] error
+// without a stack trace uses its Error() string as message and its LogValue() result as error value.
+// For joined errors, the message attribute uses the first stack trace found in the joined errors.
+func NewErrorReportingHandler(w io.Writer, opts *slog.HandlerOptions, options ...Option) slog.Handler {
+	return NewHandler(w, append([]Option{WithHandlerOptions(opts)}, options...)...)
+}
+
+// NewHandler outputs GCP compatible JSON logs to the given writer,
+// the same as [NewErrorReportingHandler].
+// All configuration, including the [slog.HandlerOptions], is passed as options.
+// Options are applied in order, so that later options take precedence.
+// Without options, [DefaultOpts] are used.
+//
+// The returned handler provides Flush() error and Close() error methods,
+// which flush and close w if it supports it.
+// They can be used through a type assertion, for a clean shutdown.
+//
+// The handler is safe for concurrent use.
+// Records are formatted concurrently and each record is written to w
+// with a single Write call, which is serialized by a mutex.
+// Each record is terminated by exactly one newline, unless disabled by [WithNewline].
+// Each record is fully formatted before it is written, so that the handler
+// never writes a partial record. Write errors are returned.
+// Common records, without errors, special fields or options which rewrite the record,
+// are streamed into a pooled buffer without an intermediate map. The output is the same.
+//
+// If w is nil, the handler writes to [os.Stdout] and a warning is written to [os.Stderr],
+// once per process.
+func NewHandler(w io.Writer, options ...Option) slog.Handler {
+	if w == nil {
+		w = nilWriterFallback()
 	}
-	if opts.Level == nil {
-		opts.Level = DefaultOpts.Level
+	return newWriterHandler(w, newConfig(options))
+}
+
+// newWriterHandler creates the handler of [NewHandler], writing to w.
+func newWriterHandler(w io.Writer, c *config) *handler {
+	if c.devMode {
+		return newHandler(&devOutput{
+			writer: &writerOutput{
+				writers:  append([]io.Writer{w}, c.tees...),
+				newline:  true,
+				writerFn: c.writerFunc,
+			},
+			timeKey: c.timeKey(),
+			utc:     c.utc,
+		}, c)
 	}
+	return newHandler(&writerOutput{
+		writers:  append([]io.Writer{w}, c.tees...),
+		newline:  c.newline,
+		syslog:   c.syslogPrefix,
+		facility: c.syslogFacility,
+		prefix:   c.indentPrefix,
+		indent:   c.indent,
+		writerFn: c.writerFunc,
+	}, c)
+}
+
+var nilWriterWarning sync.Once
+
+// nilWriterFallback returns [os.Stdout], as the writer for a nil writer passed to [NewHandler].
+// A warning is written to [os.Stderr] the first time.
+func nilWriterFallback() io.Writer {
+	nilWriterWarning.Do(func() {
+		fmt.Fprintln(os.Stderr, "sloggcp handler: nil writer, logging to stdout")
+	})
+	return os.Stdout
+}
+
+func newHandler(o output, c *config) *handler {
+	wo, ok := o.(*writerOutput)
 	return &handler{
-		opts:    opts,
-		mtx:     new(sync.Mutex),
-		encoder: json.NewEncoder(w),
+		opts:   &c.handlerOptions,
+		config: c,
+		output: o,
+		stream: ok && wo.prefix == "" && wo.indent == "" && c.streams(),
 	}
 }
 
 type handler struct {
-	opts    *slog.HandlerOptions
-	goas    []groupOrAttrs
-	mtx     *sync.Mutex // protects encoder
+	opts   *slog.HandlerOptions
+	config *config
+	goas   []groupOrAttrs
+	output output
+	// stream is set if records may be streamed by [handler.streamRecord].
+	stream bool
+}
+
+// output receives the formatted records of a handler.
+// It is shared between a handler and its derivatives.
+type output interface {
+	// write the record, formatted in state.out.
+	write(r *slog.Record, state *handleState) error
+	Flush() error
+	Close() error
+}
+
+// handleState holds the per record resources,
+// which are reused through [statePool].
+type handleState struct {
+	out     map[string]any
+	buf     bytes.Buffer
 	encoder *json.Encoder
+	fields  []streamField // of [handler.streamRecord]
+}
+
+// maxPooledBufferSize limits the buffers kept in [statePool],
+// so that a single large record doesn't keep memory allocated.
+const maxPooledBufferSize = 64 << 10
+
+var statePool = sync.Pool{
+	New: func() any {
+		s := &handleState{
+			out: make(map[string]any, 8),
+		}
+		s.encoder = json.NewEncoder(&s.buf)
+		return s
+	},
+}
+
+func (s *handleState) free() {
+	if s.buf.Cap() > maxPooledBufferSize {
+		return
+	}
+	clear(s.out)
+	clear(s.fields)
+	s.fields = s.fields[:0]
+	s.buf.Reset()
+	statePool.Put(s)
 }
 
 // Enabled implements [slog.Handler].
@@ -114,56 +270,93 @@ func (h *handler) Enabled(_ context.Context, level slog.Level) bool {
 }
 
 // Handle implements [slog.Handler].
-func (h *handler) Handle(_ context.Context, r slog.Record) error {
-	n := 4 + r.NumAttrs() + len(h.goas)
-	out := make(map[string]any, n)
-	if !r.Time.IsZero() {
-		out[TimeKey] = r.Time.Format(time.RFC3339Nano)
+func (h *handler) Handle(ctx context.Context, r slog.Record) error {
+	if !h.sampled(ctx, &r) {
+		return nil
 	}
-	if h.opts.AddSource {
-		if source := r.Source(); source != nil {
-			out[SourceLocationKey] = source
+	state := statePool.Get().(*handleState)
+	defer state.free()
+	if h.stream {
+		if ok, err := h.streamRecord(ctx, &r, state); ok {
+			return err
 		}
 	}
+	out := state.out
+	if !r.Time.IsZero() {
+		out[h.config.timeKey()] = h.config.timeValue(r.Time)
+	}
+	if h.config.addsSource(r.Level) {
+		h.config.setSource(&r, out)
+	}
 	if r.Message != "" {
 		out[MessageKey] = r.Message
 	}
+	h.config.setConfigLabels(out)
 	// Handle state from WithGroup and WithAttrs.
 	goas := h.goas
-	out[SeverityKey] = severityFromLevel(r.Level)
+	out[SeverityKey] = h.config.severityValue(r.Level)
 	if r.NumAttrs() == 0 {
 		// If the record has no Attrs, remove groups at the end of the list; they are empty.
 		for len(goas) > 0 && goas[len(goas)-1].group != "" {
 			goas = goas[:len(goas)-1]
 		}
 	}
-	// Try to find error attributes only in top-level attrs.
-	for _, goa := range goas {
-		if goa.group != "" {
-			break
+	var (
+		groups    []string
+		group     = out
+		groupMaps []map[string]any // of groups, for removing empty groups
+		// error attribute to report and the group it was found in.
+		// Top-level error attributes take precedence over grouped ones.
+		errAttr     slog.Attr
+		errGroup    map[string]any
+		errTopLevel bool
+		errPriority = -1
+	)
+	findError := func(a slog.Attr) {
+		p := h.config.errorPriority(a)
+		if p < 0 || isNil(a.Value.Any()) {
+			return
 		}
-		for _, a := range goa.attrs {
-			if checkAndSetErrorReport(a, out) {
-				break
-			}
+		topLevel := len(groups) == 0
+		if errPriority < 0 || (topLevel && !errTopLevel) || (topLevel == errTopLevel && p <= errPriority) {
+			errAttr, errGroup, errTopLevel, errPriority = a, group, topLevel, p
 		}
 	}
-
-	var (
-		groups []string
-		group  = out
-	)
+	if attrs := contextAttrs(ctx); len(attrs) > 0 {
+		h.setContextAttrs(attrs, out, findError)
+	}
 	for _, goa := range goas {
 		if goa.group != "" {
-			// start a new group
-			newGroup := make(map[string]any)
+			// start a new group, which continues an object of the same key,
+			// such as the group of [WithContextAttrsGroup].
+			// The object is copied, as it may be a cached attribute value.
+			newGroup, _ := group[goa.group].(map[string]any)
+			if newGroup != nil {
+				newGroup = maps.Clone(newGroup)
+			} else {
+				newGroup = make(map[string]any)
+			}
 			group[goa.group] = newGroup
 			group = newGroup
 			groups = append(groups, goa.group)
+			groupMaps = append(groupMaps, newGroup)
 		} else {
-			for _, a := range goa.attrs {
-				a = h.replaceAttr(groups, a)
-				group[a.Key] = a.Value.Any()
+			for _, p := range goa.prepared {
+				if p.cached {
+					group[p.Key] = p.value
+					continue
+				}
+				a := p.Attr
+				if h.config.checkAndSetHoisted(a, out) {
+					continue
+				}
+				if len(groups) == 0 && checkAndSetMerged(a, out) {
+					continue
+				}
+				findError(a)
+				if v, ok := h.extractHoisted(groups, a, out); ok {
+					group[a.Key] = v
+				}
 			}
 		}
 	}
@@ -171,30 +364,242 @@ func (h *handler) Handle(_ context.Context, r slog.Record) error {
 	// handle record attrs
 	r.Attrs(func(a slog.Attr) bool {
 		a = h.replaceAttr(groups, a)
-		if len(groups) == 0 {
-			checkAndSetErrorReport(a, out)
+		if a.Equal(slog.Attr{}) || h.config.checkAndSetHoisted(a, out) {
+			return true
+		}
+		if len(groups) == 0 && checkAndSetMerged(a, out) {
+			return true
+		}
+		findError(a)
+		if v, ok := h.extractHoisted(groups, a, out); ok {
+			group[a.Key] = v
 		}
-		group[a.Key] = extractValue(a.Value)
 		return true
 	})
-	h.mtx.Lock()
-	defer h.mtx.Unlock()
-	if err := h.encoder.Encode(out); err != nil {
+	if errPriority >= 0 {
+		if h.config.reportsError(r.Level) {
+			if suppressed, ok := ctx.Value(dedupSummaryContextKey{}).(int); ok {
+				out[DedupSummaryKey] = dedupSummary(suppressed)
+			} else if d := h.config.dedup; d != nil {
+				ok, suppressed := d.allow(dedupKey(errorAttrValue(errAttr), r.PC), h.config.timeOrNow(r.Time),
+					dedupRecord{h: h, ctx: ctx, r: r})
+				if !ok {
+					return nil
+				}
+				if suppressed > 0 {
+					out[DedupSummaryKey] = dedupSummary(suppressed)
+				}
+			}
+			h.setErrorReport(&r, errAttr, errGroup, out)
+			if fn := h.config.errorReportCallback; fn != nil {
+				fn(r.Clone())
+			}
+		} else {
+			errGroup[errAttr.Key] = errorValue(errorAttrValue(errAttr))
+		}
+		if h.config.errorChain {
+			// an attribute of the same key is kept
+			if _, ok := errGroup[ErrorCausesKey]; !ok {
+				if causes := errorCauses(errorAttrValue(errAttr)); len(causes) > 0 {
+					errGroup[ErrorCausesKey] = causes
+				}
+			}
+		}
+	}
+	setTraceFields(ctx, h.config.projectID, out)
+	// Remove empty groups, innermost first. Groups containing a group are not empty.
+	for i := len(groupMaps) - 1; i >= 0 && len(groupMaps[i]) == 0; i-- {
+		parent := out
+		if i > 0 {
+			parent = groupMaps[i-1]
+		}
+		delete(parent, groups[i])
+	}
+	if _, ok := out[InsertIDKey]; !ok && h.config.autoInsertID {
+		out[InsertIDKey] = newInsertID(&r)
+	}
+	h.config.redact(out)
+	h.config.groupPayload(out)
+	return h.output.write(&r, state)
+}
+
+// setContextAttrs sets the attributes from [ContextWithAttrs] in out,
+// or in the group set by [WithContextAttrsGroup].
+// Special fields are only handled at the top level.
+func (h *handler) setContextAttrs(attrs []slog.Attr, out map[string]any, findError func(slog.Attr)) {
+	var groups []string
+	group := out
+	if name := h.config.contextAttrsGroup; name != "" {
+		groups = []string{name}
+		group = make(map[string]any, len(attrs))
+		out[name] = group
+	}
+	for _, a := range attrs {
+		a = h.replaceAttr(groups, a)
+		if a.Equal(slog.Attr{}) || h.config.checkAndSetHoisted(a, out) {
+			continue
+		}
+		if len(groups) == 0 {
+			if checkAndSetMerged(a, out) {
+				continue
+			}
+			findError(a)
+		}
+		if v, ok := h.extractHoisted(groups, a, out); ok {
+			group[a.Key] = v
+		}
+	}
+}
+
+// checkAndSetMerged merges top-level attributes of special fields,
+// which may be set multiple times, such as [ErrorUser].
+// It returns true if a was handled.
+func checkAndSetMerged(a slog.Attr, out map[string]any) bool {
+	return checkAndSetErrorContext(a, out)
+}
+
+// checkAndSetHoisted sets special fields which are always emitted at the top level,
+// regardless of the group of a, such as [Labels], [InsertID], [Operation], [SpanID] and [Trace].
+// It returns true if a was handled.
+func (c *config) checkAndSetHoisted(a slog.Attr, out map[string]any) bool {
+	return c.checkAndSetLabels(a, out) || checkAndSetInsertID(a, out) ||
+		checkAndSetOperation(a, out) || checkAndSetSpanID(a, out) || checkAndSetTrace(a, out)
+}
+
+// Flush flushes the underlying writer, if it implements a Flush() error method.
+// Otherwise it is a no-op.
+// The summaries of errors suppressed by [WithErrorDedup] are reported first.
+func (h *handler) Flush() error {
+	return errors.Join(h.config.reportPending(), h.output.Flush())
+}
+
+// Close flushes and closes the underlying writer,
+// if it implements a Flush() error method or [io.Closer].
+// Otherwise it is a no-op.
+// The summaries of errors suppressed by [WithErrorDedup] are reported first.
+// Handlers derived with WithAttrs or WithGroup share the same writer
+// and must not be used after Close.
+func (h *handler) Close() error {
+	return errors.Join(h.config.reportPending(), h.output.Close())
+}
+
+// flusher is implemented by buffered writers, such as [bufio.Writer].
+type flusher interface {
+	Flush() error
+}
+
+// writerOutput writes each record as a line of JSON to all writers.
+// A failing writer doesn't prevent the other writers from receiving the record.
+type writerOutput struct {
+	mtx     sync.Mutex // protects writers
+	writers []io.Writer
+	newline bool
+	// facility of the syslog priority prefix, if syslog is set by [WithSyslogPrefix].
+	syslog   bool
+	facility int
+	// indentation set by [WithIndent]
+	prefix, indent string
+	// selects the first writer per record, as set by [WithWriterFunc]
+	writerFn func(slog.Level) io.Writer
+}
+
+func (o *writerOutput) write(r *slog.Record, state *handleState) error {
+	if o.syslog {
+		state.buf.WriteString(syslogPriority(o.facility, severityName(state.out[SeverityKey])))
+	}
+	// The encoder is pooled, so the indentation is reset after use.
+	if o.prefix != "" || o.indent != "" {
+		state.encoder.SetIndent(o.prefix, o.indent)
+		defer state.encoder.SetIndent("", "")
+	}
+	// Encode terminates the JSON value with exactly one newline.
+	if err := state.encoder.Encode(state.out); err != nil {
 		return fmt.Errorf("sloggcp handler: %w", err)
 	}
+	return o.writeData(r, state.buf.Bytes())
+}
+
+// writeData writes the encoded record data, which is terminated by a newline, to all writers.
+func (o *writerOutput) writeData(r *slog.Record, data []byte) error {
+	if !o.newline {
+		data = data[:len(data)-1]
+	}
+	primary := o.writers[0]
+	if o.writerFn != nil {
+		if w := o.writerFn(r.Level); w != nil {
+			primary = w
+		}
+	}
+	o.mtx.Lock()
+	defer o.mtx.Unlock()
+	var errs []error
+	for i, w := range o.writers {
+		if i == 0 {
+			w = primary
+		}
+		if _, err := w.Write(data); err != nil {
+			errs = append(errs, fmt.Errorf("sloggcp handler: %w", err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (o *writerOutput) Flush() error {
+	o.mtx.Lock()
+	defer o.mtx.Unlock()
+	var errs []error
+	for _, w := range o.writers {
+		errs = append(errs, flush(w))
+	}
+	return errors.Join(errs...)
+}
+
+func (o *writerOutput) Close() error {
+	o.mtx.Lock()
+	defer o.mtx.Unlock()
+	var errs []error
+	for _, w := range o.writers {
+		errs = append(errs, closeWriter(w))
+	}
+	return errors.Join(errs...)
+}
+
+func flush(w any) error {
+	if f, ok := w.(flusher); ok {
+		if err := f.Flush(); err != nil {
+			return fmt.Errorf("sloggcp handler: %w", err)
+		}
+	}
 	return nil
 }
 
+func closeWriter(w any) error {
+	err := flush(w)
+	if c, ok := w.(io.Closer); ok {
+		if cerr := c.Close(); cerr != nil {
+			err = errors.Join(err, fmt.Errorf("sloggcp handler: %w", cerr))
+		}
+	}
+	return err
+}
+
 func (h *handler) replaceAttr(groups []string, a slog.Attr) slog.Attr {
+	if isForceAttr(a) {
+		return slog.Attr{}
+	}
 	if h.opts.ReplaceAttr != nil {
 		a = h.opts.ReplaceAttr(groups, a)
 	}
+	if len(groups) == 0 {
+		a = h.config.checkReserved(a)
+	}
 	return a
 }
 
 // WithAttrs implements [slog.Handler].
+// The attrs are copied, so that the caller may reuse the slice.
 func (h *handler) WithAttrs(attrs []slog.Attr) slog.Handler {
-	return h.withGroupOrAttrs(groupOrAttrs{attrs: attrs})
+	return h.withGroupOrAttrs(groupOrAttrs{attrs: slices.Clone(attrs)})
 }
 
 // WithGroup implements [slog.Handler].
@@ -204,26 +609,120 @@ func (h *handler) WithGroup(name string) slog.Handler {
 
 // groupOrAttrs holds either a group name or a list of slog.Attrs.
 type groupOrAttrs struct {
-	group string      // group name if non-empty
-	attrs []slog.Attr // attrs if non-empty
+	group    string         // group name if non-empty
+	attrs    []slog.Attr    // attrs if non-empty
+	prepared []preparedAttr // attrs after ReplaceAttr
 }
 
 func (h *handler) withGroupOrAttrs(goa groupOrAttrs) *handler {
+	if len(goa.attrs) > 0 {
+		goa.prepared = h.prepareAttrs(goa.attrs)
+	}
 	h2 := *h
+	h2.stream = h.stream && goa.group == "" && !slices.ContainsFunc(goa.prepared, func(p preparedAttr) bool { return !p.cached })
 	h2.goas = make([]groupOrAttrs, len(h.goas)+1)
 	copy(h2.goas, h.goas)
 	h2.goas[len(h2.goas)-1] = goa
 	return &h2
 }
 
+// preparedAttr is an attribute passed to [handler.WithAttrs], after ReplaceAttr.
+// If cached is true, value is the output for the attribute,
+// which is computed once instead of for every record.
+type preparedAttr struct {
+	slog.Attr
+	value  any
+	cached bool
+}
+
+// prepareAttrs applies ReplaceAttr to attrs, which are added to the groups of h.
+// Attributes which are not special to the handler have their value extracted,
+// and values which are no scalars are encoded to a JSON fragment,
+// like [slog.JSONHandler] does. Special attributes, such as labels, errors and
+// values with nested special attributes, are handled for every record.
+func (h *handler) prepareAttrs(attrs []slog.Attr) []preparedAttr {
+	var groups []string
+	for _, goa := range h.goas {
+		if goa.group != "" {
+			groups = append(groups, goa.group)
+		}
+	}
+	prepared := make([]preparedAttr, 0, len(attrs))
+	scratch := make(map[string]any)
+	for _, a := range attrs {
+		a = h.replaceAttr(groups, a)
+		if a.Equal(slog.Attr{}) {
+			continue
+		}
+		p := preparedAttr{Attr: a}
+		topLevel := len(groups) == 0
+		if !h.config.checkAndSetHoisted(a, scratch) && !(topLevel && (specialKeys[a.Key] || checkAndSetMerged(a, scratch))) &&
+			h.config.errorPriority(a) < 0 {
+			v, ok := h.extractHoisted(groups, a, scratch)
+			if !ok && len(scratch) == 0 {
+				// empty group
+				continue
+			}
+			p.value, p.cached = v, len(scratch) == 0
+			if p.cached {
+				p.value = h.config.fragment(p.value)
+			}
+		}
+		clear(scratch)
+		prepared = append(prepared, p)
+	}
+	return prepared
+}
+
+// fragment returns the JSON encoding of the extracted value v,
+// or v itself for scalar values, or for the dev output which formats values itself.
+// Redacted keys in nested objects are redacted before encoding.
+func (c *config) fragment(v any) any {
+	switch tv := v.(type) {
+	case nil, string, int64, uint64, float64, bool, time.Time:
+		return v
+	default:
+		v, _ = c.redactValue(tv)
+	}
+	if c.devMode {
+		return v
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		// the error is returned when the record is written
+		return v
+	}
+	return json.RawMessage(data)
+}
+
 func extractValue(v slog.Value) any {
-	if v.Kind() == slog.KindGroup {
-		m := make(map[string]any)
-		attr := v.Group()
-		for _, a := range attr {
+	// Scalar kinds are returned directly, without the type assertions below.
+	// The results are identical.
+	switch v.Kind() {
+	case slog.KindGroup:
+		attrs := v.Group()
+		m := make(map[string]any, len(attrs))
+		for _, a := range attrs {
 			m[a.Key] = extractValue(a.Value)
 		}
 		return m
+	case slog.KindString:
+		return v.String()
+	case slog.KindInt64:
+		return v.Int64()
+	case slog.KindUint64:
+		return v.Uint64()
+	case slog.KindFloat64:
+		return v.Float64()
+	case slog.KindBool:
+		return v.Bool()
+	case slog.KindDuration:
+		return v.Duration().String()
+	case slog.KindTime:
+		return v.Time()
+	}
+	if isNil(v.Any()) {
+		return nil
 	}
 	switch tv := v.Any().(type) {
 	case slog.LogValuer:
@@ -234,12 +733,156 @@ func extractValue(v slog.Value) any {
 		return tv.Error()
 	case fmt.Stringer:
 		return tv.String()
+	case []error:
+		list := make([]any, len(tv))
+		for i, err := range tv {
+			list[i] = extractValue(slog.AnyValue(err))
+		}
+		return list
+	case []any:
+		if !slices.ContainsFunc(tv, containsError) {
+			return tv
+		}
+		list := make([]any, len(tv))
+		for i, e := range tv {
+			list[i] = extractValue(slog.AnyValue(e))
+		}
+		return list
+	case map[string]any:
+		if !containsError(tv) {
+			return tv
+		}
+		m := make(map[string]any, len(tv))
+		for k, e := range tv {
+			m[k] = extractValue(slog.AnyValue(e))
+		}
+		return m
 	default:
 		return tv
 	}
 }
 
-func severityFromLevel(level slog.Level) string {
+// containsError reports whether v is an error, or a []any or map[string]any holding errors.
+// The JSON encoding of most error types is an empty object,
+// so [extractValue] renders them with their Error method instead.
+func containsError(v any) bool {
+	switch tv := v.(type) {
+	case error:
+		return true
+	case []error:
+		return len(tv) > 0
+	case []any:
+		return slices.ContainsFunc(tv, containsError)
+	case map[string]any:
+		for _, e := range tv {
+			if containsError(e) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// isNil reports whether v is nil, or a nil pointer, map, slice, function or channel
+// stored in an interface, such as a typed nil error.
+func isNil(v any) bool {
+	if v == nil {
+		return true
+	}
+	switch rv := reflect.ValueOf(v); rv.Kind() {
+	case reflect.Pointer, reflect.Map, reflect.Slice, reflect.Func, reflect.Chan, reflect.Interface:
+		return rv.IsNil()
+	}
+	return false
+}
+
+// extractHoisted returns the value of a, the same as [extractValue],
+// but sets the members of groups which are handled by [checkAndSetHoisted] in out, instead of the group.
+// ReplaceAttr is applied to the members of groups, where groups are the groups of a.
+// It returns false for groups which are empty, for example after members were dropped by ReplaceAttr,
+// so that they can be omitted like [slog.JSONHandler] does.
+func (h *handler) extractHoisted(groups []string, a slog.Attr, out map[string]any) (any, bool) {
+	v := a.Value.Resolve()
+	if v.Kind() != slog.KindGroup {
+		return extractValue(v), true
+	}
+	groups = append(slices.Clip(groups), a.Key)
+	m := make(map[string]any)
+	for _, member := range v.Group() {
+		member = h.replaceAttr(groups, member)
+		if member.Equal(slog.Attr{}) || h.config.checkAndSetHoisted(member, out) {
+			continue
+		}
+		if value, ok := h.extractHoisted(groups, member, out); ok {
+			m[member.Key] = value
+		}
+	}
+	return m, len(m) > 0
+}
+
+// severity returns the severity field value for level,
+// using the mapper set by [WithSeverityMapper].
+func (c *config) severity(level slog.Level) string {
+	return c.severityMapper(level)
+}
+
+// severityValue returns the severity field value for level,
+// which is numeric when [WithNumericSeverity] is enabled.
+func (c *config) severityValue(level slog.Level) any {
+	return c.severityField(c.severity(level))
+}
+
+// severityField returns the severity field value for severity,
+// as formatted by [WithNumericSeverity] or [WithLowercaseSeverity].
+func (c *config) severityField(severity string) any {
+	switch {
+	case c.numericSeverity:
+		return SeverityNumber(severity)
+	case c.lowercaseSeverity:
+		return strings.ToLower(severity)
+	}
+	return severity
+}
+
+// severityNumbers maps severities to their google.logging.type.LogSeverity enum values.
+var severityNumbers = map[string]int{
+	DefaultSeverity:   0,
+	DebugSeverity:     100,
+	InfoSeverity:      200,
+	NoticeSeverity:    300,
+	WarningSeverity:   400,
+	ErrorSeverity:     500,
+	CriticalSeverity:  600,
+	AlertSeverity:     700,
+	EmergencySeverity: 800,
+}
+
+// SeverityNumber returns the numeric value of severity in the google.logging.type.LogSeverity enum,
+// such as 400 for [WarningSeverity]. Unknown severities map to 0, the value of [DefaultSeverity].
+func SeverityNumber(severity string) int {
+	return severityNumbers[severity]
+}
+
+// severityName returns the severity of the severity field value v,
+// which may be numeric or lowercase as set by [WithNumericSeverity] or [WithLowercaseSeverity].
+func severityName(v any) string {
+	switch v := v.(type) {
+	case string:
+		return strings.ToUpper(v)
+	case int:
+		for name, n := range severityNumbers {
+			if n == v {
+				return name
+			}
+		}
+	}
+	return ""
+}
+
+// SeverityFromLevel maps a [slog.Level] to a GCP severity, as used by the handler and [ReplaceAttr].
+// Levels in between the defined constants are rounded down
+// to the nearest lower severity. Levels below [LevelDebug] map to [DefaultSeverity].
+func SeverityFromLevel(level slog.Level) string {
 	if level >= LevelEmergency {
 		return EmergencySeverity
 	}
@@ -1,6 +1,7 @@
 package sloggcp_test
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"log/slog"
@@ -48,6 +49,16 @@ func (e AppError) LogValue() slog.Value {
 	)
 }
 
+// fixedTimeHandler sets a fixed record time, for deterministic output.
+type fixedTimeHandler struct {
+	slog.Handler
+}
+
+func (h fixedTimeHandler) Handle(ctx context.Context, r slog.Record) error {
+	r.Time = time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	return h.Handler.Handle(ctx, r)
+}
+
 func ExampleNewErrorReportingHandler() {
 	h := sloggcp.NewErrorReportingHandler(os.Stdout, &slog.HandlerOptions{
 		Level: slog.LevelInfo,
@@ -62,7 +73,7 @@ func ExampleNewErrorReportingHandler() {
 			return a
 		},
 	})
-	logger := slog.New(h).With(sloggcp.TimeKey, time.Time{}) // for deterministic output
+	logger := slog.New(fixedTimeHandler{h}) // for deterministic output
 
 	// Simple string error
 	logger.Error("", "err", errors.New("something went wrong"))
@@ -79,6 +90,6 @@ func ExampleNewErrorReportingHandler() {
 	}
 	logger.Error("", "err", err)
 	// Output:
-	// {"@type":"type.googleapis.com/google.devtools.clouderrorreporting.v1beta1.ReportedErrorEvent","error":"something went wrong","message":"something went wrong","severity":"ERROR","time":"0001-01-01T00:00:00Z"}
-	// {"@type":"type.googleapis.com/google.devtools.clouderrorreporting.v1beta1.ReportedErrorEvent","error":{"message":"failed to fetch user data","parent":"database connection failed"},"message":"[STACK TRACE]","reportLocation":{"filePath":"user_service.go","lineNumber":42,"functionName":"fetchUserData"},"severity":"ERROR","time":"0001-01-01T00:00:00Z"}
+	// {"@type":"type.googleapis.com/google.devtools.clouderrorreporting.v1beta1.ReportedErrorEvent","error":"something went wrong","eventTime":"2025-01-01T00:00:00Z","message":"something went wrong","severity":"ERROR","time":"2025-01-01T00:00:00Z"}
+	// {"@type":"type.googleapis.com/google.devtools.clouderrorreporting.v1beta1.ReportedErrorEvent","error":{"message":"failed to fetch user data","parent":"database connection failed"},"eventTime":"2025-01-01T00:00:00Z","message":"[STACK TRACE]","reportLocation":{"filePath":"user_service.go","lineNumber":42,"functionName":"fetchUserData"},"severity":"ERROR","time":"2025-01-01T00:00:00Z"}
 }
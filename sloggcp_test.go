@@ -4,9 +4,17 @@ import (
 	"bytes"
 	"encoding/json"
 	"errors"
+	"io"
 	"log/slog"
+	"maps"
+	"os"
 	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 )
 
 type stringer struct{}
@@ -129,7 +137,8 @@ func TestHandler(t *testing.T) {
 				logger.Warn("warn message", slog.String("error", "grouped error"))
 			},
 			want: &expectSchema{
-				Message:  "warn message",
+				Type:     ErrorReportTypeValue,
+				Message:  "grouped error",
 				Severity: WarningSeverity,
 				Group: groupType{
 					Bar:   "baz",
@@ -138,6 +147,44 @@ func TestHandler(t *testing.T) {
 				},
 			},
 		},
+		{
+			name: "log error with group and attrs",
+			log: func(logger *slog.Logger) {
+				logger = logger.WithGroup("group")
+				logger = logger.With(
+					slog.String("bar", "baz"),
+					slog.Int("baz", 42),
+				)
+				logger.Error("error message", "error", mockReportLocationError{})
+			},
+			want: &expectSchema{
+				Type:     ErrorReportTypeValue,
+				Message:  "mockReportLocationError",
+				Severity: ErrorSeverity,
+				Group: groupType{
+					Bar:   "baz",
+					Baz:   42,
+					Error: "mockReportLocationError",
+				},
+				ReportLocation: mockReportLocation,
+			},
+		},
+		{
+			name: "log error top-level error precedes grouped error",
+			log: func(logger *slog.Logger) {
+				logger = logger.With("error", "top-level error").WithGroup("group")
+				logger.Error("error message", "error", "grouped error")
+			},
+			want: &expectSchema{
+				Type:     ErrorReportTypeValue,
+				Message:  "top-level error",
+				Severity: ErrorSeverity,
+				Error:    "top-level error",
+				Group: groupType{
+					Error: "grouped error",
+				},
+			},
+		},
 		{
 			name: "log info grouped without attrs",
 			log: func(logger *slog.Logger) {
@@ -298,7 +345,7 @@ func TestHandler(t *testing.T) {
 	}
 }
 
-func Test_severityFromLevel(t *testing.T) {
+func TestSeverityFromLevel(t *testing.T) {
 	tests := []struct {
 		name  string
 		level slog.Level
@@ -344,6 +391,21 @@ func Test_severityFromLevel(t *testing.T) {
 			level: LevelEmergency,
 			want:  EmergencySeverity,
 		},
+		{
+			name:  "Between Info and Notice",
+			level: LevelInfo + 1,
+			want:  InfoSeverity,
+		},
+		{
+			name:  "Between Error and Critical",
+			level: LevelError + 1,
+			want:  ErrorSeverity,
+		},
+		{
+			name:  "Above Emergency",
+			level: LevelEmergency + 10,
+			want:  EmergencySeverity,
+		},
 		{
 			name:  "Default",
 			level: Level(-10),
@@ -352,9 +414,1008 @@ func Test_severityFromLevel(t *testing.T) {
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			got := severityFromLevel(tt.level)
+			got := SeverityFromLevel(tt.level)
 			if got != tt.want {
-				t.Errorf("severityFromLevel() = %v, want %v", got, tt.want)
+				t.Errorf("SeverityFromLevel() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHandler_severity(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(NewErrorReportingHandler(&buf, &slog.HandlerOptions{
+		Level: Level(-10),
+	}))
+	dec := json.NewDecoder(&buf)
+
+	tests := []struct {
+		level Level
+		want  string
+	}{
+		{LevelDebug, DebugSeverity},
+		{LevelInfo, InfoSeverity},
+		{LevelNotice, NoticeSeverity},
+		{LevelWarning, WarningSeverity},
+		{LevelError, ErrorSeverity},
+		{LevelCritical, CriticalSeverity},
+		{LevelAlert, AlertSeverity},
+		{LevelEmergency, EmergencySeverity},
+		{LevelInfo + 1, InfoSeverity},
+		{Level(-10), DefaultSeverity},
+	}
+	for _, tt := range tests {
+		t.Run(tt.level.String(), func(t *testing.T) {
+			defer buf.Reset()
+			logger.Log(t.Context(), tt.level, "test message")
+			var got expectSchema
+			if err := dec.Decode(&got); err != nil {
+				t.Fatalf("Failed to decode log output: %v", err)
+			}
+			if got.Severity != tt.want {
+				t.Errorf("severity = %v, want %v", got.Severity, tt.want)
+			}
+		})
+	}
+}
+
+func TestWithNumericSeverity(t *testing.T) {
+	tests := []struct {
+		level Level
+		want  int
+	}{
+		{Level(-10), 0},
+		{LevelDebug, 100},
+		{LevelInfo, 200},
+		{LevelNotice, 300},
+		{LevelWarning, 400},
+		{LevelError, 500},
+		{LevelCritical, 600},
+		{LevelAlert, 700},
+		{LevelEmergency, 800},
+	}
+	var handlerBuf, replaceBuf bytes.Buffer
+	handlers := map[string]struct {
+		h   slog.Handler
+		buf *bytes.Buffer
+	}{
+		"handler": {NewHandler(&handlerBuf, WithMinLevel(Level(-10)), WithNumericSeverity(true)), &handlerBuf},
+		"ReplaceAttr": {slog.NewJSONHandler(&replaceBuf, &slog.HandlerOptions{
+			Level:       Level(-10),
+			ReplaceAttr: NewReplaceAttr(WithNumericSeverity(true)),
+		}), &replaceBuf},
+	}
+	for name, h := range handlers {
+		logger := slog.New(h.h)
+		for _, tt := range tests {
+			t.Run(name+"/"+tt.level.String(), func(t *testing.T) {
+				defer h.buf.Reset()
+				logger.Log(t.Context(), tt.level, "test message")
+				var got struct {
+					Severity int `json:"severity"`
+				}
+				if err := json.Unmarshal(h.buf.Bytes(), &got); err != nil {
+					t.Fatalf("Failed to decode log output: %v", err)
+				}
+				if got.Severity != tt.want {
+					t.Errorf("severity = %v, want %v", got.Severity, tt.want)
+				}
+			})
+		}
+	}
+}
+
+func TestWithLowercaseSeverity(t *testing.T) {
+	tests := []struct {
+		level Level
+		want  string
+	}{
+		{Level(-10), "default"},
+		{LevelDebug, "debug"},
+		{LevelInfo, "info"},
+		{LevelNotice, "notice"},
+		{LevelWarning, "warning"},
+		{LevelError, "error"},
+		{LevelCritical, "critical"},
+		{LevelAlert, "alert"},
+		{LevelEmergency, "emergency"},
+	}
+	var handlerBuf, replaceBuf bytes.Buffer
+	handlers := map[string]struct {
+		h   slog.Handler
+		buf *bytes.Buffer
+	}{
+		"handler": {NewHandler(&handlerBuf, WithMinLevel(Level(-10)), WithLowercaseSeverity(true)), &handlerBuf},
+		"ReplaceAttr": {slog.NewJSONHandler(&replaceBuf, &slog.HandlerOptions{
+			Level:       Level(-10),
+			ReplaceAttr: NewReplaceAttr(WithLowercaseSeverity(true)),
+		}), &replaceBuf},
+	}
+	for name, h := range handlers {
+		logger := slog.New(h.h)
+		for _, tt := range tests {
+			t.Run(name+"/"+tt.level.String(), func(t *testing.T) {
+				defer h.buf.Reset()
+				logger.Log(t.Context(), tt.level, "test message")
+				var got struct {
+					Severity string `json:"severity"`
+				}
+				if err := json.Unmarshal(h.buf.Bytes(), &got); err != nil {
+					t.Fatalf("Failed to decode log output: %v", err)
+				}
+				if got.Severity != tt.want {
+					t.Errorf("severity = %v, want %v", got.Severity, tt.want)
+				}
+			})
+		}
+	}
+}
+
+func Test_severityName(t *testing.T) {
+	tests := []struct {
+		v    any
+		want string
+	}{
+		{ErrorSeverity, ErrorSeverity},
+		{"error", ErrorSeverity},
+		{500, ErrorSeverity},
+		{nil, ""},
+	}
+	for _, tt := range tests {
+		if got := severityName(tt.v); got != tt.want {
+			t.Errorf("severityName(%v) = %q, want %q", tt.v, got, tt.want)
+		}
+	}
+}
+
+func TestSeverityNumber(t *testing.T) {
+	for severity, want := range severityNumbers {
+		if got := SeverityNumber(severity); got != want {
+			t.Errorf("SeverityNumber(%q) = %d, want %d", severity, got, want)
+		}
+		if got := severityName(want); got != severity {
+			t.Errorf("severityName(%d) = %q, want %q", want, got, severity)
+		}
+	}
+	if got := SeverityNumber("UNKNOWN"); got != 0 {
+		t.Errorf("SeverityNumber(UNKNOWN) = %d, want 0", got)
+	}
+}
+
+func TestHandler_duplicateKeys(t *testing.T) {
+	tests := []struct {
+		name string
+		log  func(*slog.Logger)
+		want map[string]any
+	}{
+		{
+			name: "With and record",
+			log: func(l *slog.Logger) {
+				l.With("foo", 1).Info("test message", "foo", 2)
+			},
+			want: map[string]any{"foo": float64(2)},
+		},
+		{
+			name: "multiple With",
+			log: func(l *slog.Logger) {
+				l.With("foo", 1).With("foo", 2).Info("test message")
+			},
+			want: map[string]any{"foo": float64(2)},
+		},
+		{
+			name: "record",
+			log: func(l *slog.Logger) {
+				l.Info("test message", "foo", 1, "foo", 2)
+			},
+			want: map[string]any{"foo": float64(2)},
+		},
+		{
+			name: "group",
+			log: func(l *slog.Logger) {
+				l.WithGroup("group").With("foo", 1).Info("test message", "foo", 2)
+			},
+			want: map[string]any{"group": map[string]any{"foo": float64(2)}},
+		},
+		{
+			name: "different groups",
+			log: func(l *slog.Logger) {
+				l.With("foo", 1).WithGroup("group").Info("test message", "foo", 2)
+			},
+			want: map[string]any{"foo": float64(1), "group": map[string]any{"foo": float64(2)}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for range 10 {
+				var buf bytes.Buffer
+				tt.log(slog.New(NewHandler(&buf)))
+
+				var got map[string]any
+				if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+					t.Fatalf("Failed to decode log output: %v", err)
+				}
+				for key, want := range tt.want {
+					if !reflect.DeepEqual(got[key], want) {
+						t.Fatalf("%s = %v, want %v", key, got[key], want)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestHandler_newline(t *testing.T) {
+	t.Run("enabled", func(t *testing.T) {
+		var buf bytes.Buffer
+		logger := slog.New(NewHandler(&buf))
+		logger.Info("first message")
+		logger.Error("second message", "error", "multi\nline")
+
+		data := buf.Bytes()
+		if !bytes.HasSuffix(data, []byte("}\n")) || bytes.HasSuffix(data, []byte("\n\n")) {
+			t.Fatalf("log output not terminated by a single newline: %q", data)
+		}
+		lines := bytes.Split(bytes.TrimSuffix(data, []byte("\n")), []byte("\n"))
+		if len(lines) != 2 {
+			t.Fatalf("got %d lines, want 2: %q", len(lines), data)
+		}
+		for i, line := range lines {
+			var got expectSchema
+			if err := json.Unmarshal(line, &got); err != nil {
+				t.Errorf("line %d: Failed to decode log output: %v", i, err)
+			}
+		}
+	})
+	t.Run("disabled", func(t *testing.T) {
+		var w writesRecorder
+		logger := slog.New(NewHandler(&w, WithNewline(false)))
+		logger.Info("first message")
+		logger.Info("second message")
+
+		if len(w.writes) != 2 {
+			t.Fatalf("got %d writes, want 2", len(w.writes))
+		}
+		for i, data := range w.writes {
+			if bytes.HasSuffix(data, []byte("\n")) {
+				t.Errorf("write %d: unexpected newline: %q", i, data)
+			}
+			var got expectSchema
+			if err := json.Unmarshal(data, &got); err != nil {
+				t.Errorf("write %d: Failed to decode log output: %v", i, err)
+			}
+		}
+	})
+}
+
+func TestWithIndent(t *testing.T) {
+	log := func(h slog.Handler) {
+		slog.New(h).With("with", map[string]any{"key": 1}).
+			Error("test message", "error", "multi\nline", "group", groupTypeTest)
+	}
+	var indented, compact writesRecorder
+	log(NewHandler(&indented, WithIndent("", "  ")))
+	log(NewHandler(&compact))
+
+	if len(indented.writes) != 1 {
+		t.Fatalf("got %d writes, want 1", len(indented.writes))
+	}
+	if !bytes.Contains(indented.writes[0], []byte("\n  \"")) {
+		t.Errorf("output not indented: %s", indented.writes[0])
+	}
+	// the pooled encoder must not keep the indentation
+	if n := bytes.Count(compact.writes[0], []byte("\n")); n != 1 {
+		t.Errorf("compact output has %d newlines, want 1: %s", n, compact.writes[0])
+	}
+	var got, want map[string]any
+	if err := json.Unmarshal(indented.writes[0], &got); err != nil {
+		t.Fatalf("Failed to decode indented log output: %v", err)
+	}
+	if err := json.Unmarshal(compact.writes[0], &want); err != nil {
+		t.Fatalf("Failed to decode compact log output: %v", err)
+	}
+	for _, m := range []map[string]any{got, want} {
+		delete(m, TimeKey)
+		delete(m, EventTimeKey)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("indented output =\n%v\nwant\n%v", got, want)
+	}
+}
+
+// writesRecorder records the data of each Write call.
+type writesRecorder struct {
+	writes [][]byte
+}
+
+func (w *writesRecorder) Write(p []byte) (int, error) {
+	w.writes = append(w.writes, bytes.Clone(p))
+	return len(p), nil
+}
+
+var benchmarkError = errors.New("benchmark error")
+
+// performanceCases are the records of the benchmarks of [NewErrorReportingHandler],
+// with an upper bound of allocations per record, which guards against regressions.
+// The bounds leave headroom for differences between Go versions.
+var performanceCases = map[string]struct {
+	with      func(*slog.Logger) *slog.Logger
+	log       func(*slog.Logger)
+	maxAllocs float64
+}{
+	"Info": {
+		log: func(l *slog.Logger) {
+			l.Info("benchmark message", "count", 42, "name", "value")
+		},
+		maxAllocs: 10,
+	},
+	"Error": {
+		log: func(l *slog.Logger) {
+			l.Error("benchmark message", "error", benchmarkError, "count", 42)
+		},
+		maxAllocs: 45,
+	},
+	"WithAttrs": {
+		with: func(l *slog.Logger) *slog.Logger {
+			return l.With("service", "benchmark", "version", 1, "group", groupTypeTest)
+		},
+		log: func(l *slog.Logger) {
+			l.Info("benchmark message", "count", 42)
+		},
+		maxAllocs: 10,
+	},
+	"GroupedError": {
+		with: func(l *slog.Logger) *slog.Logger {
+			return l.WithGroup("request").With("id", "abc")
+		},
+		log: func(l *slog.Logger) {
+			l.Error("benchmark message", "error", benchmarkError, slog.Group("details", "count", 42))
+		},
+		maxAllocs: 55,
+	},
+}
+
+// performanceLogger returns the logger of the performance case name, which discards the output.
+func performanceLogger(name string) (*slog.Logger, func(*slog.Logger)) {
+	pc := performanceCases[name]
+	logger := slog.New(NewErrorReportingHandler(io.Discard, nil))
+	if pc.with != nil {
+		logger = pc.with(logger)
+	}
+	return logger, pc.log
+}
+
+func benchmarkPerformanceCase(b *testing.B, name string) {
+	logger, log := performanceLogger(name)
+	b.ReportAllocs()
+	for b.Loop() {
+		log(logger)
+	}
+}
+
+func BenchmarkInfo(b *testing.B)         { benchmarkPerformanceCase(b, "Info") }
+func BenchmarkError(b *testing.B)        { benchmarkPerformanceCase(b, "Error") }
+func BenchmarkWithAttrs(b *testing.B)    { benchmarkPerformanceCase(b, "WithAttrs") }
+func BenchmarkGroupedError(b *testing.B) { benchmarkPerformanceCase(b, "GroupedError") }
+
+func TestHandler_allocs(t *testing.T) {
+	for name, pc := range performanceCases {
+		t.Run(name, func(t *testing.T) {
+			logger, log := performanceLogger(name)
+			if got := testing.AllocsPerRun(100, func() { log(logger) }); got > pc.maxAllocs {
+				t.Errorf("allocs per record = %v, want at most %v", got, pc.maxAllocs)
+			}
+		})
+	}
+}
+
+func BenchmarkHandle(b *testing.B) {
+	logger := slog.New(NewHandler(io.Discard)).With("foo", "bar")
+	b.ReportAllocs()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			logger.Info("benchmark message", "count", 42, "group", groupTypeTest)
+		}
+	})
+}
+
+func TestHandler_concurrent(t *testing.T) {
+	const (
+		goroutines = 50
+		records    = 20
+	)
+	var buf bytes.Buffer
+	logger := slog.New(NewHandler(&buf))
+
+	var wg sync.WaitGroup
+	for i := range goroutines {
+		wg.Go(func() {
+			l := logger.With("goroutine", i)
+			for j := range records {
+				l.Info("concurrent message", "record", j, "group", groupTypeTest)
+			}
+		})
+	}
+	wg.Wait()
+
+	lines := bytes.Split(bytes.TrimSuffix(buf.Bytes(), []byte("\n")), []byte("\n"))
+	if len(lines) != goroutines*records {
+		t.Fatalf("got %d lines, want %d", len(lines), goroutines*records)
+	}
+	for i, line := range lines {
+		var got expectSchema
+		if err := json.Unmarshal(line, &got); err != nil {
+			t.Fatalf("line %d: %v: %q", i, err, line)
+		}
+		if got.Message != "concurrent message" {
+			t.Errorf("line %d: message = %q, want %q", i, got.Message, "concurrent message")
+		}
+	}
+}
+
+type flushCloser struct {
+	bytes.Buffer
+	flushed, closed bool
+	err             error
+}
+
+func (w *flushCloser) Flush() error {
+	w.flushed = true
+	return w.err
+}
+
+func (w *flushCloser) Close() error {
+	w.closed = true
+	return w.err
+}
+
+func TestHandler_FlushClose(t *testing.T) {
+	type flushCloseHandler interface {
+		slog.Handler
+		Flush() error
+		io.Closer
+	}
+	errWrite := errors.New("write error")
+
+	t.Run("plain writer", func(t *testing.T) {
+		h := NewHandler(&bytes.Buffer{}).(flushCloseHandler)
+		if err := h.Flush(); err != nil {
+			t.Errorf("Flush() = %v, want nil", err)
+		}
+		if err := h.Close(); err != nil {
+			t.Errorf("Close() = %v, want nil", err)
+		}
+	})
+	t.Run("flush", func(t *testing.T) {
+		w := new(flushCloser)
+		h := NewHandler(w).WithGroup("group").(flushCloseHandler)
+		if err := h.Flush(); err != nil {
+			t.Errorf("Flush() = %v, want nil", err)
+		}
+		if !w.flushed || w.closed {
+			t.Errorf("flushed = %v, closed = %v, want true, false", w.flushed, w.closed)
+		}
+	})
+	t.Run("close", func(t *testing.T) {
+		w := new(flushCloser)
+		h := NewHandler(w).(flushCloseHandler)
+		if err := h.Close(); err != nil {
+			t.Errorf("Close() = %v, want nil", err)
+		}
+		if !w.flushed || !w.closed {
+			t.Errorf("flushed = %v, closed = %v, want true, true", w.flushed, w.closed)
+		}
+	})
+	t.Run("errors", func(t *testing.T) {
+		w := &flushCloser{err: errWrite}
+		h := NewHandler(w).(flushCloseHandler)
+		if err := h.Flush(); !errors.Is(err, errWrite) {
+			t.Errorf("Flush() = %v, want %v", err, errWrite)
+		}
+		if err := h.Close(); !errors.Is(err, errWrite) {
+			t.Errorf("Close() = %v, want %v", err, errWrite)
+		}
+	})
+}
+
+func nestedGroupValue(depth int) slog.Value {
+	attrs := []slog.Attr{
+		slog.String("string", "value"),
+		slog.Int("int", 42),
+		slog.Bool("bool", true),
+		slog.Float64("float", 1.5),
+		slog.Duration("duration", time.Second),
+	}
+	if depth > 1 {
+		attrs = append(attrs, slog.Any("nested", nestedGroupValue(depth-1)))
+	}
+	return slog.GroupValue(attrs...)
+}
+
+func Test_extractValue_nested(t *testing.T) {
+	want := map[string]any{
+		"string":   "value",
+		"int":      int64(42),
+		"bool":     true,
+		"float":    1.5,
+		"duration": "1s",
+		"nested": map[string]any{
+			"string":   "value",
+			"int":      int64(42),
+			"bool":     true,
+			"float":    1.5,
+			"duration": "1s",
+		},
+	}
+	if got := extractValue(nestedGroupValue(2)); !reflect.DeepEqual(got, want) {
+		t.Errorf("extractValue() = %v, want %v", got, want)
+	}
+}
+
+type causeLogValuer struct{}
+
+func (causeLogValuer) LogValue() slog.Value {
+	return slog.GroupValue(slog.Any("cause", errors.New("nested")))
+}
+
+func TestHandler_errorValues(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(NewHandler(&buf)).With("with", errors.New("from with"))
+	logger.Info("test message",
+		slog.Any("cause", errors.New("boom")),
+		slog.Any("valuer", causeLogValuer{}),
+		slog.Any("list", []any{errors.New("first"), 1}),
+		slog.Any("errs", []error{errors.New("a"), nil}),
+		slog.Any("map", map[string]any{"err": errors.New("in map")}),
+	)
+
+	var got map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+		t.Fatalf("Failed to decode log output: %v", err)
+	}
+	want := map[string]any{
+		"with":   "from with",
+		"cause":  "boom",
+		"valuer": map[string]any{"cause": "nested"},
+		"list":   []any{"first", float64(1)},
+		"errs":   []any{"a", nil},
+		"map":    map[string]any{"err": "in map"},
+	}
+	for k, v := range want {
+		if !reflect.DeepEqual(got[k], v) {
+			t.Errorf("%s = %v, want %v", k, got[k], v)
+		}
+	}
+}
+
+func BenchmarkExtractValue(b *testing.B) {
+	v := nestedGroupValue(4)
+	b.ReportAllocs()
+	for b.Loop() {
+		extractValue(v)
+	}
+}
+
+type failingWriter struct {
+	err error
+}
+
+func (w failingWriter) Write([]byte) (int, error) {
+	return 0, w.err
+}
+
+func TestWithTees(t *testing.T) {
+	errWrite := errors.New("write error")
+	var primary bytes.Buffer
+	secondary := new(flushCloser)
+	h := NewHandler(&primary, WithTees(failingWriter{errWrite}, secondary))
+
+	r := slog.NewRecord(time.Now(), slog.LevelInfo, "test message", 0)
+	if err := h.Handle(t.Context(), r); !errors.Is(err, errWrite) {
+		t.Errorf("Handle() = %v, want %v", err, errWrite)
+	}
+	if primary.Len() == 0 || primary.String() != secondary.String() {
+		t.Errorf("primary = %q, secondary = %q, want the same record", primary.String(), secondary.String())
+	}
+
+	if err := h.(interface{ Close() error }).Close(); err != nil {
+		t.Errorf("Close() = %v, want nil", err)
+	}
+	if !secondary.closed {
+		t.Error("tee not closed")
+	}
+
+	var dev bytes.Buffer
+	devTee := new(flushCloser)
+	h = NewDevHandler(&dev, WithTees(devTee))
+	slog.New(h).Info("test message")
+	if dev.Len() == 0 || dev.String() != devTee.String() {
+		t.Errorf("dev = %q, tee = %q, want the same line", dev.String(), devTee.String())
+	}
+	if err := h.(interface{ Close() error }).Close(); err != nil || !devTee.closed {
+		t.Errorf("dev Close() = %v, tee closed = %v, want nil, true", err, devTee.closed)
+	}
+}
+
+func TestWithWriterFunc(t *testing.T) {
+	var stdout, stderr, tee writesRecorder
+	var fallback bytes.Buffer
+	logger := slog.New(NewHandler(&fallback,
+		WithMinLevel(slog.LevelDebug),
+		WithTees(&tee),
+		WithWriterFunc(func(level slog.Level) io.Writer {
+			switch {
+			case level >= slog.LevelError:
+				return &stderr
+			case level >= slog.LevelInfo:
+				return &stdout
+			default:
+				return nil
+			}
+		}),
+	))
+	logger.Info("info message")
+	logger.Error("error message", "error", errors.New("boom"))
+	logger.Debug("debug message")
+
+	for name, tt := range map[string]struct {
+		writes [][]byte
+		want   []string
+	}{
+		"stdout": {stdout.writes, []string{InfoSeverity}},
+		"stderr": {stderr.writes, []string{ErrorSeverity}},
+		"tee":    {tee.writes, []string{InfoSeverity, ErrorSeverity, DebugSeverity}},
+	} {
+		if len(tt.writes) != len(tt.want) {
+			t.Fatalf("%s: got %d writes, want %d", name, len(tt.writes), len(tt.want))
+		}
+		for i, data := range tt.writes {
+			var got expectSchema
+			if err := json.Unmarshal(data, &got); err != nil {
+				t.Fatalf("%s: Failed to decode log output: %v", name, err)
+			}
+			if got.Severity != tt.want[i] {
+				t.Errorf("%s: write %d severity = %s, want %s", name, i, got.Severity, tt.want[i])
+			}
+		}
+	}
+	if !strings.Contains(fallback.String(), "debug message") || strings.Count(fallback.String(), "\n") != 1 {
+		t.Errorf("fallback writer = %q, want the debug record", fallback.String())
+	}
+
+	var devOut, devErr bytes.Buffer
+	dev := slog.New(NewDevHandler(&devOut, WithWriterFunc(func(level slog.Level) io.Writer {
+		if level >= slog.LevelError {
+			return &devErr
+		}
+		return nil
+	})))
+	dev.Info("info message")
+	dev.Error("error message")
+	if !strings.Contains(devOut.String(), "info message") || strings.Contains(devOut.String(), "error message") {
+		t.Errorf("dev writer = %q, want the info record only", devOut.String())
+	}
+	if !strings.Contains(devErr.String(), "error message") || strings.Contains(devErr.String(), "info message") {
+		t.Errorf("dev error writer = %q, want the error record only", devErr.String())
+	}
+}
+
+// limitWriter accepts whole writes up to limit bytes in total,
+// and fails once a write would exceed it.
+type limitWriter struct {
+	buf   bytes.Buffer
+	limit int
+	calls int
+}
+
+var errLimit = errors.New("write limit reached")
+
+func (w *limitWriter) Write(p []byte) (int, error) {
+	w.calls++
+	if w.buf.Len()+len(p) > w.limit {
+		return 0, errLimit
+	}
+	return w.buf.Write(p)
+}
+
+func TestHandler_writeError(t *testing.T) {
+	w := &limitWriter{limit: 300}
+	logger := slog.New(NewHandler(w))
+	h := logger.Handler()
+
+	var failed int
+	for i := range 10 {
+		r := slog.NewRecord(time.Now(), slog.LevelInfo, "test message", 0)
+		r.AddAttrs(slog.Int("record", i), slog.String("padding", strings.Repeat("x", 20)))
+		if err := h.Handle(t.Context(), r); err != nil {
+			if !errors.Is(err, errLimit) {
+				t.Fatalf("Handle() = %v, want %v", err, errLimit)
+			}
+			failed++
+		}
+	}
+	if failed == 0 {
+		t.Fatal("no write failed")
+	}
+	if w.calls != 10 {
+		t.Errorf("got %d Write calls, want one per record", w.calls)
+	}
+	data := w.buf.Bytes()
+	if !bytes.HasSuffix(data, []byte("\n")) {
+		t.Fatalf("stream ends with a truncated record: %q", data)
+	}
+	for line := range bytes.Lines(data) {
+		var got expectSchema
+		if err := json.Unmarshal(line, &got); err != nil {
+			t.Errorf("truncated record %q: %v", line, err)
+		}
+	}
+}
+
+func TestHandler_withAttrsFragments(t *testing.T) {
+	var withBuf, recordBuf bytes.Buffer
+	options := []Option{WithRedactKeys("secret")}
+	value := map[string]any{"id": 1, "secret": "password"}
+
+	withLogger := slog.New(NewHandler(&withBuf, options...)).
+		With("a", value, Labels("tenant", "acme")).
+		WithGroup("g").
+		With("b", groupTypeTest, slog.Group("inline", InsertID("id-1"))).
+		WithGroup("h")
+	withLogger.Info("test message", "c", []int{1, 2})
+
+	recordLogger := slog.New(NewHandler(&recordBuf, options...))
+	recordLogger.Info("test message",
+		"a", value, Labels("tenant", "acme"),
+		slog.Group("g",
+			"b", groupTypeTest, slog.Group("inline", InsertID("id-1")),
+			slog.Group("h", "c", []int{1, 2}),
+		),
+	)
+
+	var got, want map[string]any
+	if err := json.Unmarshal(withBuf.Bytes(), &got); err != nil {
+		t.Fatalf("Failed to decode log output: %v", err)
+	}
+	if err := json.Unmarshal(recordBuf.Bytes(), &want); err != nil {
+		t.Fatalf("Failed to decode log output: %v", err)
+	}
+	delete(got, TimeKey)
+	delete(want, TimeKey)
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("With output =\n%v\nwant\n%v", got, want)
+	}
+	if a := got["a"].(map[string]any); a["secret"] != RedactedValue {
+		t.Errorf("secret = %v, want %v", a["secret"], RedactedValue)
+	}
+
+	// the cached fragments of the parent are not modified by a derived handler
+	withBuf.Reset()
+	withLogger.With("d", "value").Info("test message")
+	withBuf.Reset()
+	withLogger.Info("test message", "c", []int{1, 2})
+	if err := json.Unmarshal(withBuf.Bytes(), &got); err != nil {
+		t.Fatalf("Failed to decode log output: %v", err)
+	}
+	delete(got, TimeKey)
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("With output after derive =\n%v\nwant\n%v", got, want)
+	}
+}
+
+func TestHandler_WithAttrs_aliasing(t *testing.T) {
+	var buf bytes.Buffer
+	base := slog.New(NewHandler(&buf)).With("base", 0)
+	a := base.With("a", 1)
+	b := base.With("b", 2)
+
+	// a reused attrs slice must not affect the handler
+	attrs := make([]slog.Attr, 1, 2)
+	attrs[0] = slog.Int("c", 3)
+	h := base.Handler()
+	c := slog.New(h.WithAttrs(attrs))
+	attrs[0] = slog.Int("overwritten", 0)
+	d := slog.New(h.WithAttrs(append(attrs, slog.Int("d", 4))))
+
+	tests := []struct {
+		name   string
+		logger *slog.Logger
+		want   []string
+	}{
+		{"base", base, []string{"base"}},
+		{"a", a, []string{"base", "a"}},
+		{"b", b, []string{"base", "b"}},
+		{"c", c, []string{"base", "c"}},
+		{"d", d, []string{"base", "overwritten", "d"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			buf.Reset()
+			tt.logger.Info("test message")
+			var got map[string]any
+			if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+				t.Fatalf("Failed to decode log output: %v", err)
+			}
+			for _, k := range []string{TimeKey, SeverityKey, MessageKey} {
+				delete(got, k)
+			}
+			keys := slices.Sorted(maps.Keys(got))
+			want := slices.Sorted(slices.Values(tt.want))
+			if !slices.Equal(keys, want) {
+				t.Errorf("keys = %v, want %v", keys, want)
+			}
+		})
+	}
+}
+
+func BenchmarkHandle_withAttrs(b *testing.B) {
+	attrs := make([]any, 0, 10)
+	for i := range 10 {
+		attrs = append(attrs, slog.Any("attr"+strconv.Itoa(i), map[string]any{"index": i, "name": "value"}))
+	}
+	b.Run("record attrs", func(b *testing.B) {
+		logger := slog.New(NewHandler(io.Discard))
+		b.ReportAllocs()
+		for b.Loop() {
+			logger.Info("benchmark message", attrs...)
+		}
+	})
+	b.Run("With attrs", func(b *testing.B) {
+		logger := slog.New(NewHandler(io.Discard)).With(attrs...)
+		b.ReportAllocs()
+		for b.Loop() {
+			logger.Info("benchmark message")
+		}
+	})
+}
+
+func TestHandler_emptyGroups(t *testing.T) {
+	dropSecrets := func(groups []string, a slog.Attr) slog.Attr {
+		if a.Key == "password" || a.Key == "token" {
+			return slog.Attr{}
+		}
+		return a
+	}
+	tests := []struct {
+		name string
+		log  func(*slog.Logger)
+		want map[string]any
+	}{
+		{
+			name: "nested inline group dropped by ReplaceAttr",
+			log: func(l *slog.Logger) {
+				l.Info("test message", "foo", "bar", slog.Group("outer", slog.Group("inner", "password", "secret")))
+			},
+			want: map[string]any{"foo": "bar"},
+		},
+		{
+			name: "nested WithGroup dropped by ReplaceAttr",
+			log: func(l *slog.Logger) {
+				l.WithGroup("outer").With("token", "secret").WithGroup("inner").Info("test message", "password", "secret")
+			},
+			want: map[string]any{},
+		},
+		{
+			name: "inner group empty, outer group kept",
+			log: func(l *slog.Logger) {
+				l.WithGroup("outer").With("foo", "bar").WithGroup("inner").Info("test message", "password", "secret")
+			},
+			want: map[string]any{"outer": map[string]any{"foo": "bar"}},
+		},
+		{
+			name: "group with hoisted labels only",
+			log: func(l *slog.Logger) {
+				l.Info("test message", slog.Group("outer", Labels("tenant", "acme")))
+			},
+			want: map[string]any{LabelsKey: map[string]any{"tenant": "acme"}},
+		},
+		{
+			name: "With empty inline group",
+			log: func(l *slog.Logger) {
+				l.With(slog.Group("outer", "password", "secret")).Info("test message")
+			},
+			want: map[string]any{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			tt.log(slog.New(NewHandler(&buf, WithHandlerOptions(&slog.HandlerOptions{ReplaceAttr: dropSecrets}))))
+
+			var got map[string]any
+			if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+				t.Fatalf("Failed to decode log output: %v", err)
+			}
+			for _, k := range []string{TimeKey, SeverityKey, MessageKey} {
+				delete(got, k)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("log output = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewHandler_nilWriter(t *testing.T) {
+	nilWriterWarning = sync.Once{}
+	stdout, stderr := os.Stdout, os.Stderr
+	t.Cleanup(func() { os.Stdout, os.Stderr = stdout, stderr })
+	outR, outW, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	errR, errW, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	os.Stdout, os.Stderr = outW, errW
+
+	slog.New(NewErrorReportingHandler(nil, nil)).Info("test message")
+	slog.New(NewHandler(nil)).Info("test message")
+	outW.Close()
+	errW.Close()
+	os.Stdout, os.Stderr = stdout, stderr
+
+	out, err := io.ReadAll(outR)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := bytes.Count(out, []byte(`"message":"test message"`)); n != 2 {
+		t.Errorf("stdout records = %d, want 2\n%s", n, out)
+	}
+	warning, err := io.ReadAll(errR)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := bytes.Count(warning, []byte("nil writer")); n != 1 {
+		t.Errorf("stderr warnings = %d, want 1\n%s", n, warning)
+	}
+}
+
+func TestHandler_escaping(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+	}{
+		{"newline", "first line\nsecond line\r\n"},
+		{"null byte", "before\x00after"},
+		{"control characters", "\a\b\f\t\v\x1b[31m\x7f"},
+		{"quotes and backslashes", `"quoted" \escaped\ \"`},
+		{"html", "<script>alert('&')</script>"},
+		{"line separators", "a\u2028b\u2029c"},
+		{"invalid UTF-8", "lone \x80 byte, truncated \xe2\x82"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			logger := slog.New(NewHandler(&buf))
+			logger.Info(tt.input, tt.input, tt.input)
+			logger.Error("error message", "error", errors.New(tt.input))
+
+			// encoding/json replaces each invalid UTF-8 byte with the replacement character,
+			// as does the conversion to runes.
+			want := string([]rune(tt.input))
+			lines := bytes.Split(bytes.TrimSuffix(buf.Bytes(), []byte("\n")), []byte("\n"))
+			if len(lines) != 2 {
+				t.Fatalf("got %d lines, want 2:\n%s", len(lines), buf.Bytes())
+			}
+			for i, line := range lines {
+				if !json.Valid(line) {
+					t.Fatalf("line %d is invalid JSON: %q", i, line)
+				}
+				var got map[string]any
+				if err := json.Unmarshal(line, &got); err != nil {
+					t.Fatalf("Failed to decode log output: %v", err)
+				}
+				if got[MessageKey] != want {
+					t.Errorf("line %d: message = %q, want %q", i, got[MessageKey], want)
+				}
+			}
+			var got map[string]any
+			if err := json.Unmarshal(lines[0], &got); err != nil {
+				t.Fatalf("Failed to decode log output: %v", err)
+			}
+			if got[want] != want {
+				t.Errorf("attribute = %q, want %q", got[want], want)
 			}
 		})
 	}
@@ -0,0 +1,72 @@
+package sloggcp
+
+import (
+	"encoding/json"
+	"log/slog"
+	"strings"
+)
+
+// gcpSource is the value of the [SourceLocationKey] special field.
+// It is encoded like [slog.Source], with empty fields omitted.
+type gcpSource slog.Source
+
+// MarshalJSON implements [json.Marshaler].
+func (s gcpSource) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Function string `json:"function,omitempty"`
+		File     string `json:"file,omitempty"`
+		Line     int    `json:"line,omitempty"`
+	}{s.Function, s.File, s.Line})
+}
+
+// isEmpty reports whether s has no function, file and line.
+func (s *gcpSource) isEmpty() bool {
+	return s.Function == "" && s.File == "" && s.Line == 0
+}
+
+// source returns src with the configured modifications applied, as a [gcpSource].
+// src is not modified.
+func (c *config) source(src *slog.Source) *gcpSource {
+	if c.sourceTrimPrefix == "" && !c.shortFunction {
+		return (*gcpSource)(src)
+	}
+	out := gcpSource(*src)
+	out.File = strings.TrimPrefix(out.File, c.sourceTrimPrefix)
+	if c.shortFunction {
+		out.Function = shortFunction(out.Function)
+	}
+	return &out
+}
+
+// shortFunction removes the package path from the fully qualified function name fn,
+// such as "github.com/x/y.Foo", which becomes "Foo".
+// Methods and closures keep their receiver and suffix, such as "(*T).Method" and "Foo.func1".
+func shortFunction(fn string) string {
+	// dots in the last element of the package path are escaped as %2e
+	if i := strings.LastIndexByte(fn, '/'); i >= 0 {
+		fn = fn[i+1:]
+	}
+	if i := strings.IndexByte(fn, '.'); i >= 0 {
+		fn = fn[i+1:]
+	}
+	return fn
+}
+
+// setSource sets the [SourceLocationKey] field in out from the program counter of r.
+// Empty sources are omitted.
+func (c *config) setSource(r *slog.Record, out map[string]any) {
+	if source := r.Source(); source != nil {
+		if gs := c.source(source); !gs.isEmpty() {
+			out[SourceLocationKey] = gs
+		}
+	}
+}
+
+// addsSource reports whether records of level get a source location,
+// as set by [WithSourceMinLevel] or the AddSource handler option.
+func (c *config) addsSource(level slog.Level) bool {
+	if c.sourceMinLevel != nil {
+		return level >= c.sourceMinLevel.Level()
+	}
+	return c.handlerOptions.AddSource
+}
@@ -0,0 +1,289 @@
+package sloggcp
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWithSourceTrimPrefix(t *testing.T) {
+	_, file, _, _ := runtime.Caller(0)
+	prefix := filepath.Dir(file) + "/"
+	opts := &slog.HandlerOptions{AddSource: true}
+
+	handlers := map[string]slog.Handler{}
+	bufs := map[string]*bytes.Buffer{}
+	for _, name := range []string{"handler", "ReplaceAttr"} {
+		bufs[name] = new(bytes.Buffer)
+	}
+	handlers["handler"] = NewHandler(bufs["handler"], WithHandlerOptions(opts), WithSourceTrimPrefix(prefix))
+	handlers["ReplaceAttr"] = slog.NewJSONHandler(bufs["ReplaceAttr"], &slog.HandlerOptions{
+		AddSource:   true,
+		ReplaceAttr: NewReplaceAttr(WithSourceTrimPrefix(prefix)),
+	})
+
+	for name, h := range handlers {
+		t.Run(name, func(t *testing.T) {
+			slog.New(h).Info("test message")
+			var got struct {
+				Source slog.Source `json:"logging.googleapis.com/sourceLocation"`
+			}
+			if err := json.Unmarshal(bufs[name].Bytes(), &got); err != nil {
+				t.Fatalf("Failed to decode log output: %v", err)
+			}
+			if got.Source.File != "source_test.go" {
+				t.Errorf("source file = %q, want %q", got.Source.File, "source_test.go")
+			}
+		})
+	}
+}
+
+func Test_config_source(t *testing.T) {
+	src := &slog.Source{File: "/build/pkg/file.go", Line: 1}
+	c := newConfig([]Option{WithSourceTrimPrefix("/build/")})
+	got := c.source(src)
+	if got.File != "pkg/file.go" {
+		t.Errorf("source() file = %q, want %q", got.File, "pkg/file.go")
+	}
+	if src.File != "/build/pkg/file.go" {
+		t.Errorf("source() modified the original source: %q", src.File)
+	}
+	if got := newConfig(nil).source(src); (*slog.Source)(got) != src {
+		t.Errorf("source() without prefix = %v, want original", got)
+	}
+}
+
+func Test_shortFunction(t *testing.T) {
+	tests := []struct {
+		fn   string
+		want string
+	}{
+		{"github.com/x/y.Foo", "Foo"},
+		{"github.com/x/y.(*T).Method", "(*T).Method"},
+		{"github.com/x/y.Foo.func1", "Foo.func1"},
+		{"gopkg.in/yaml%2ev3.Unmarshal", "Unmarshal"},
+		{"main.main", "main"},
+		{"Foo", "Foo"},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		if got := shortFunction(tt.fn); got != tt.want {
+			t.Errorf("shortFunction(%q) = %q, want %q", tt.fn, got, tt.want)
+		}
+	}
+}
+
+func TestWithSourceFunctionShortening(t *testing.T) {
+	src := slog.Source{Function: "github.com/x/y.Foo", File: "/src/y/foo.go", Line: 1}
+	want := gcpSource{Function: "Foo", File: "/src/y/foo.go", Line: 1}
+	c := newConfig([]Option{WithSourceFunctionShortening(true)})
+	if got := c.source(&src); *got != want {
+		t.Errorf("source() = %v, want %v", got, want)
+	}
+	if src.Function != "github.com/x/y.Foo" {
+		t.Errorf("source() modified the original source: %q", src.Function)
+	}
+
+	handlers := map[string]func(*bytes.Buffer) slog.Handler{
+		"handler": func(buf *bytes.Buffer) slog.Handler {
+			return NewHandler(buf, WithHandlerOptions(&slog.HandlerOptions{AddSource: true}), WithSourceFunctionShortening(true))
+		},
+		"ReplaceAttr": func(buf *bytes.Buffer) slog.Handler {
+			return slog.NewJSONHandler(buf, &slog.HandlerOptions{
+				AddSource:   true,
+				ReplaceAttr: NewReplaceAttr(WithSourceFunctionShortening(true)),
+			})
+		},
+	}
+	for name, newHandler := range handlers {
+		t.Run(name, func(t *testing.T) {
+			var buf bytes.Buffer
+			slog.New(newHandler(&buf)).Info("test message")
+			var got struct {
+				Source slog.Source `json:"logging.googleapis.com/sourceLocation"`
+			}
+			if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+				t.Fatalf("Failed to decode log output: %v", err)
+			}
+			// the function is a closure of the test
+			if fn := got.Source.Function; !strings.HasPrefix(fn, "TestWithSourceFunctionShortening.func") {
+				t.Errorf("source function = %q, want the test function without package path", fn)
+			}
+		})
+	}
+}
+
+func Test_gcpSource_MarshalJSON(t *testing.T) {
+	tests := []struct {
+		name string
+		src  gcpSource
+		want string
+	}{
+		{
+			name: "complete",
+			src:  gcpSource{Function: "pkg.Func", File: "file.go", Line: 12},
+			want: `{"function":"pkg.Func","file":"file.go","line":12}`,
+		},
+		{
+			name: "line 0",
+			src:  gcpSource{Function: "pkg.Func", File: "file.go"},
+			want: `{"function":"pkg.Func","file":"file.go"}`,
+		},
+		{
+			name: "empty",
+			want: `{}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := json.Marshal(&tt.src)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("MarshalJSON() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHandler_emptySource(t *testing.T) {
+	var buf bytes.Buffer
+	h := NewHandler(&buf, WithHandlerOptions(&slog.HandlerOptions{AddSource: true}))
+	// a PC without frame information results in an empty source
+	r := slog.NewRecord(time.Now(), slog.LevelInfo, "test message", 1)
+	if err := h.Handle(t.Context(), r); err != nil {
+		t.Fatal(err)
+	}
+	var got map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+		t.Fatalf("Failed to decode log output: %v", err)
+	}
+	if v, ok := got[SourceLocationKey]; ok {
+		t.Errorf("unexpected %s: %v", SourceLocationKey, v)
+	}
+}
+
+func TestWithSourceMinLevel(t *testing.T) {
+	tests := []struct {
+		name    string
+		options []Option
+		level   slog.Level
+		want    bool
+	}{
+		{
+			name:    "below min level",
+			options: []Option{WithSourceMinLevel(slog.LevelWarn)},
+			level:   slog.LevelInfo,
+			want:    false,
+		},
+		{
+			name:    "at min level",
+			options: []Option{WithSourceMinLevel(slog.LevelWarn)},
+			level:   slog.LevelWarn,
+			want:    true,
+		},
+		{
+			name:    "below min level with AddSource",
+			options: []Option{WithHandlerOptions(&slog.HandlerOptions{AddSource: true}), WithSourceMinLevel(slog.LevelWarn)},
+			level:   slog.LevelInfo,
+			want:    false,
+		},
+		{
+			name:    "AddSource",
+			options: []Option{WithHandlerOptions(&slog.HandlerOptions{AddSource: true})},
+			level:   slog.LevelInfo,
+			want:    true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			slog.New(NewHandler(&buf, tt.options...)).Log(t.Context(), tt.level, "test message")
+			var got map[string]any
+			if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+				t.Fatalf("Failed to decode log output: %v", err)
+			}
+			if _, ok := got[SourceLocationKey]; ok != tt.want {
+				t.Errorf("%s present = %v, want %v", SourceLocationKey, ok, tt.want)
+			}
+		})
+	}
+}
+
+func BenchmarkHandle_source(b *testing.B) {
+	for _, bb := range []struct {
+		name    string
+		options []Option
+	}{
+		{"AddSource", []Option{WithHandlerOptions(&slog.HandlerOptions{AddSource: true})}},
+		{"SourceMinLevel", []Option{WithSourceMinLevel(slog.LevelWarn)}},
+	} {
+		b.Run(bb.name, func(b *testing.B) {
+			logger := slog.New(NewHandler(io.Discard, bb.options...))
+			b.ReportAllocs()
+			for b.Loop() {
+				logger.Info("benchmark message", "count", 42)
+			}
+		})
+	}
+}
+
+func TestWithErrorSource(t *testing.T) {
+	tests := []struct {
+		name    string
+		options []Option
+		log     func(*slog.Logger)
+		want    bool
+	}{
+		{
+			name:    "error report",
+			options: []Option{WithErrorSource(true)},
+			log:     func(l *slog.Logger) { l.Error("test message", "error", errors.New("boom")) },
+			want:    true,
+		},
+		{
+			name:    "no error",
+			options: []Option{WithErrorSource(true)},
+			log:     func(l *slog.Logger) { l.Error("test message") },
+			want:    false,
+		},
+		{
+			name:    "below error report level",
+			options: []Option{WithErrorSource(true), WithErrorReportMinLevel(slog.LevelError)},
+			log:     func(l *slog.Logger) { l.Info("test message", "error", errors.New("boom")) },
+			want:    false,
+		},
+		{
+			name: "disabled",
+			log:  func(l *slog.Logger) { l.Error("test message", "error", errors.New("boom")) },
+			want: false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			options := append([]Option{WithHandlerOptions(&slog.HandlerOptions{AddSource: false})}, tt.options...)
+			tt.log(slog.New(NewHandler(&buf, options...)))
+			var got struct {
+				Source *slog.Source `json:"logging.googleapis.com/sourceLocation"`
+			}
+			if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+				t.Fatalf("Failed to decode log output: %v", err)
+			}
+			if (got.Source != nil) != tt.want {
+				t.Fatalf("%s = %v, want present %v", SourceLocationKey, got.Source, tt.want)
+			}
+			if got.Source != nil && filepath.Base(got.Source.File) != "source_test.go" {
+				t.Errorf("source file = %q, want %q", got.Source.File, "source_test.go")
+			}
+		})
+	}
+}
@@ -0,0 +1,48 @@
+package sloggcp
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"log/slog"
+	"regexp"
+	"time"
+)
+
+// NewStdLogWriter returns a writer which logs each write as a record at level,
+// through logger. It is intended as output of the standard library [log] package:
+//
+//	log.SetOutput(sloggcp.NewStdLogWriter(logger, slog.LevelInfo))
+//
+// The date and time prefix of the [log] package is stripped, if present.
+// A single write with multiple lines is logged as a single message.
+// The records have no source location, as the caller of the [log] package is unknown.
+func NewStdLogWriter(logger *slog.Logger, level slog.Level) io.Writer {
+	return &stdLogWriter{
+		handler: logger.Handler(),
+		level:   level,
+	}
+}
+
+type stdLogWriter struct {
+	handler slog.Handler
+	level   slog.Level
+}
+
+// stdLogPrefix matches the prefix of the [log.Ldate], [log.Ltime] and [log.Lmicroseconds] flags.
+var stdLogPrefix = regexp.MustCompile(`^(\d{4}/\d{2}/\d{2} )?(\d{2}:\d{2}:\d{2}(\.\d{6})? )?`)
+
+// Write implements [io.Writer].
+func (w *stdLogWriter) Write(p []byte) (int, error) {
+	ctx := context.Background()
+	if !w.handler.Enabled(ctx, w.level) {
+		return len(p), nil
+	}
+	msg := bytes.TrimSuffix(p, []byte("\n"))
+	msg = msg[len(stdLogPrefix.Find(msg)):]
+	r := slog.NewRecord(time.Now(), w.level, string(msg), 0)
+	if err := w.handler.Handle(ctx, r); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
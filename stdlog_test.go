@@ -0,0 +1,78 @@
+package sloggcp
+
+import (
+	"bytes"
+	"encoding/json"
+	"log"
+	"log/slog"
+	"testing"
+)
+
+func TestNewStdLogWriter(t *testing.T) {
+	tests := []struct {
+		name  string
+		flags int
+		print func(*log.Logger)
+		want  string
+	}{
+		{
+			name:  "standard flags",
+			flags: log.LstdFlags,
+			print: func(l *log.Logger) { l.Printf("hello %s", "world") },
+			want:  "hello world",
+		},
+		{
+			name:  "microseconds",
+			flags: log.Ldate | log.Lmicroseconds,
+			print: func(l *log.Logger) { l.Print("hello world") },
+			want:  "hello world",
+		},
+		{
+			name:  "time only",
+			flags: log.Ltime,
+			print: func(l *log.Logger) { l.Print("hello world") },
+			want:  "hello world",
+		},
+		{
+			name:  "no flags",
+			flags: 0,
+			print: func(l *log.Logger) { l.Print("hello world") },
+			want:  "hello world",
+		},
+		{
+			name:  "multi-line",
+			flags: log.LstdFlags,
+			print: func(l *log.Logger) { l.Print("first line\nsecond line\n") },
+			want:  "first line\nsecond line",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			w := NewStdLogWriter(slog.New(NewHandler(&buf)), LevelNotice)
+			tt.print(log.New(w, "", tt.flags))
+
+			var got expectSchema
+			if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+				t.Fatalf("Failed to decode log output: %v", err)
+			}
+			if got.Message != tt.want {
+				t.Errorf("message = %q, want %q", got.Message, tt.want)
+			}
+			if got.Severity != NoticeSeverity {
+				t.Errorf("severity = %q, want %q", got.Severity, NoticeSeverity)
+			}
+		})
+	}
+}
+
+func TestNewStdLogWriter_disabled(t *testing.T) {
+	var buf bytes.Buffer
+	w := NewStdLogWriter(slog.New(NewHandler(&buf)), slog.LevelDebug)
+	if n, err := w.Write([]byte("debug\n")); n != 6 || err != nil {
+		t.Errorf("Write() = %d, %v, want 6, nil", n, err)
+	}
+	if buf.Len() != 0 {
+		t.Errorf("log output = %s, want none", buf.String())
+	}
+}
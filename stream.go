@@ -0,0 +1,265 @@
+package sloggcp
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+// streamKind determines which member of a [streamField] holds the value.
+type streamKind int
+
+const (
+	streamAny streamKind = iota
+	streamString
+	streamInt
+	streamTime
+)
+
+// streamField is a top-level field of a record written by [handler.streamRecord].
+type streamField struct {
+	key   string
+	kind  streamKind
+	str   string
+	num   int64
+	time  time.Time
+	value any
+}
+
+// streams reports whether the options allow records to be streamed by [handler.streamRecord].
+// Options which rewrite the fields of a record require the map of the slow path.
+func (c *config) streams() bool {
+	return c.handlerOptions.ReplaceAttr == nil && len(c.redactKeys) == 0 && c.payloadGroup == "" &&
+		!c.autoInsertID && c.component == "" && !c.processLabels && len(c.resourceLabels) == 0
+}
+
+// streamsAttr reports whether the top-level record attribute a can be streamed.
+// Special fields, reserved keys and error attributes require the slow path.
+func (c *config) streamsAttr(a slog.Attr) bool {
+	return !specialKeys[a.Key] && !reservedKeys[a.Key] && a.Key != c.timeKey() && c.errorPriority(a) < 0
+}
+
+// streamsGroup reports whether the members of the group value v can be streamed as extracted by [extractValue].
+// Members which are empty, hoisted special fields or empty groups require the slow path,
+// which removes them from the group.
+func streamsGroup(v slog.Value) bool {
+	for _, a := range v.Group() {
+		if a.Equal(slog.Attr{}) || isForceAttr(a) || specialKeys[a.Key] {
+			return false
+		}
+		if v := a.Value.Resolve(); v.Kind() == slog.KindGroup && (len(v.Group()) == 0 || !streamsGroup(v)) {
+			return false
+		}
+	}
+	return true
+}
+
+// streamRecord writes the common records, which have no error to report, no groups of [slog.Logger.WithGroup]
+// and no special fields, straight into the buffer of state, without the intermediate map.
+// The fields are sorted by key, so that the output is identical to the encoding of the map.
+// It returns false, without writing anything, if the record requires the slow path of [handler.Handle].
+func (h *handler) streamRecord(ctx context.Context, r *slog.Record, state *handleState) (bool, error) {
+	if len(contextAttrs(ctx)) > 0 {
+		return false, nil
+	}
+	c := h.config
+	fields := state.fields[:0]
+	ok := true
+	r.Attrs(func(a slog.Attr) bool {
+		if a.Equal(slog.Attr{}) || isForceAttr(a) {
+			return true
+		}
+		if !c.streamsAttr(a) {
+			ok = false
+			return false
+		}
+		f := streamField{key: a.Key}
+		switch v := a.Value.Resolve(); v.Kind() {
+		case slog.KindGroup:
+			if len(v.Group()) == 0 || !streamsGroup(v) {
+				ok = false
+				return false
+			}
+			f.value = extractValue(v)
+		case slog.KindString:
+			f.kind, f.str = streamString, v.String()
+		case slog.KindInt64:
+			f.kind, f.num = streamInt, v.Int64()
+		default:
+			f.value = extractValue(v)
+		}
+		fields = append(fields, f)
+		return true
+	})
+	if !ok {
+		state.fields = fields
+		return false, nil
+	}
+	for _, goa := range h.goas {
+		for _, p := range goa.prepared {
+			fields = append(fields, streamField{key: p.Key, value: p.value})
+		}
+	}
+	if !r.Time.IsZero() {
+		if c.timestamp == timestampObject {
+			fields = append(fields, streamField{key: c.timeKey(), value: c.timeValue(r.Time)})
+		} else {
+			fields = append(fields, streamField{key: c.timeKey(), kind: streamTime, time: c.zone(r.Time)})
+		}
+	}
+	if c.addsSource(r.Level) {
+		if source := r.Source(); source != nil {
+			if gs := c.source(source); !gs.isEmpty() {
+				fields = append(fields, streamField{key: SourceLocationKey, value: gs})
+			}
+		}
+	}
+	if r.Message != "" {
+		fields = append(fields, streamField{key: MessageKey, kind: streamString, str: r.Message})
+	}
+	severity := c.severity(r.Level)
+	switch {
+	case c.numericSeverity:
+		fields = append(fields, streamField{key: SeverityKey, kind: streamInt, num: int64(SeverityNumber(severity))})
+	case c.lowercaseSeverity:
+		fields = append(fields, streamField{key: SeverityKey, kind: streamString, str: strings.ToLower(severity)})
+	default:
+		fields = append(fields, streamField{key: SeverityKey, kind: streamString, str: severity})
+	}
+	if sc := trace.SpanContextFromContext(ctx); sc.IsValid() {
+		fields = append(fields,
+			streamField{key: TraceKey, kind: streamString, str: traceName(c.projectID, sc.TraceID().String())},
+			streamField{key: SpanIDKey, kind: streamString, str: sc.SpanID().String()},
+			streamField{key: TraceSampledKey, value: sc.IsSampled()},
+		)
+	}
+	state.fields = fields
+	slices.SortFunc(fields, func(a, b streamField) int {
+		return strings.Compare(a.key, b.key)
+	})
+	for i := 1; i < len(fields); i++ {
+		if fields[i].key == fields[i-1].key {
+			// the map keeps the last value of duplicate keys
+			return false, nil
+		}
+	}
+
+	o := h.output.(*writerOutput)
+	buf := &state.buf
+	if o.syslog {
+		buf.WriteString(syslogPriority(o.facility, severityName(c.severityValue(r.Level))))
+	}
+	buf.WriteByte('{')
+	for i, f := range fields {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		writeJSONString(buf, f.key)
+		buf.WriteByte(':')
+		if err := writeStreamValue(state, f); err != nil {
+			return true, fmt.Errorf("sloggcp handler: %w", err)
+		}
+	}
+	buf.WriteString("}\n")
+	return true, o.writeData(r, buf.Bytes())
+}
+
+// writeStreamValue writes the JSON encoding of the value of f to the buffer of state.
+// Values which are no scalars are written by the encoder of state.
+func writeStreamValue(state *handleState, f streamField) error {
+	buf := &state.buf
+	switch f.kind {
+	case streamString:
+		writeJSONString(buf, f.str)
+		return nil
+	case streamInt:
+		buf.Write(strconv.AppendInt(buf.AvailableBuffer(), f.num, 10))
+		return nil
+	case streamTime:
+		b := append(buf.AvailableBuffer(), '"')
+		b = f.time.AppendFormat(b, time.RFC3339Nano)
+		buf.Write(append(b, '"'))
+		return nil
+	}
+	switch v := f.value.(type) {
+	case nil:
+		buf.WriteString("null")
+	case string:
+		writeJSONString(buf, v)
+	case int64:
+		buf.Write(strconv.AppendInt(buf.AvailableBuffer(), v, 10))
+	case bool:
+		buf.Write(strconv.AppendBool(buf.AvailableBuffer(), v))
+	default:
+		// Encode terminates the JSON value with a newline, which is removed.
+		if err := state.encoder.Encode(v); err != nil {
+			return err
+		}
+		buf.Truncate(buf.Len() - 1)
+	}
+	return nil
+}
+
+const hexDigits = "0123456789abcdef"
+
+// writeJSONString writes s as a JSON string, escaped exactly as by [json.Encoder] with HTML escaping:
+// control characters, <, > and & are escaped, as are U+2028 and U+2029,
+// and invalid UTF-8 is replaced by U+FFFD.
+func writeJSONString(buf *bytes.Buffer, s string) {
+	b := append(buf.AvailableBuffer(), '"')
+	start := 0
+	for i := 0; i < len(s); {
+		if c := s[i]; c < utf8.RuneSelf {
+			if c >= 0x20 && c != '"' && c != '\\' && c != '<' && c != '>' && c != '&' {
+				i++
+				continue
+			}
+			b = append(b, s[start:i]...)
+			switch c {
+			case '"', '\\':
+				b = append(b, '\\', c)
+			case '\b':
+				b = append(b, '\\', 'b')
+			case '\f':
+				b = append(b, '\\', 'f')
+			case '\n':
+				b = append(b, '\\', 'n')
+			case '\r':
+				b = append(b, '\\', 'r')
+			case '\t':
+				b = append(b, '\\', 't')
+			default:
+				b = append(b, '\\', 'u', '0', '0', hexDigits[c>>4], hexDigits[c&0xF])
+			}
+			i++
+			start = i
+			continue
+		}
+		r, size := utf8.DecodeRuneInString(s[i:])
+		if r == utf8.RuneError && size == 1 {
+			b = append(b, s[start:i]...)
+			b = append(b, "\ufffd"...)
+			i += size
+			start = i
+			continue
+		}
+		if r == '\u2028' || r == '\u2029' {
+			b = append(b, s[start:i]...)
+			b = append(b, '\\', 'u', '2', '0', '2', hexDigits[r&0xF])
+			i += size
+			start = i
+			continue
+		}
+		i += size
+	}
+	b = append(b, s[start:]...)
+	buf.Write(append(b, '"'))
+}
@@ -0,0 +1,258 @@
+package sloggcp
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"math"
+	"net/netip"
+	"runtime"
+	"testing"
+	"time"
+	"unicode/utf8"
+)
+
+func Test_writeJSONString(t *testing.T) {
+	inputs := []string{
+		"",
+		"plain",
+		`"quoted" \escaped\`,
+		"<script>&</script>",
+		"line\nbreak\r\ttab\b\f",
+		"\x00\x01\x1f\x7f",
+		"a b c",
+		"é, 世界, 🙂",
+		"lone \x80 byte, truncated \xe2\x82",
+		"\xff\xfe",
+	}
+	for b := range 256 {
+		inputs = append(inputs, string([]byte{byte(b)}), "x"+string([]byte{byte(b)})+"y")
+	}
+	for r := rune(0x80); r < 0x3000; r += 7 {
+		inputs = append(inputs, string(r))
+	}
+	inputs = append(inputs, string(utf8.MaxRune), "\U0010ffff")
+	for _, s := range inputs {
+		want, err := json.Marshal(s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var buf bytes.Buffer
+		writeJSONString(&buf, s)
+		if !bytes.Equal(buf.Bytes(), want) {
+			t.Errorf("writeJSONString(%q) = %s, want %s", s, buf.Bytes(), want)
+		}
+	}
+}
+
+type testStringer struct{}
+
+// emptyGroupValuer resolves to an empty group, which slog doesn't drop like an empty [slog.Group].
+type emptyGroupValuer struct{}
+
+func (emptyGroupValuer) LogValue() slog.Value { return slog.GroupValue() }
+
+func (testStringer) String() string { return "stringer" }
+
+// TestHandler_streamRecord compares the output of the streaming path with the output of the map.
+func TestHandler_streamRecord(t *testing.T) {
+	var pcs [1]uintptr
+	runtime.Callers(1, pcs[:])
+	recordTime := time.Date(2025, 6, 1, 14, 4, 5, 123456789, time.FixedZone("CEST", 2*60*60))
+	traceCtx := ContextWithTrace(context.Background(), "4bf92f3577b34da6a3ce929d0e0e4736", 0xf067aa0ba902b7, true)
+
+	tests := []struct {
+		name       string
+		options    []Option
+		ctx        context.Context
+		with       func(*slog.Logger) *slog.Logger
+		attrs      []slog.Attr
+		wantStream bool
+	}{
+		{
+			name: "scalars",
+			attrs: []slog.Attr{
+				slog.String("string", "value <&>"),
+				slog.Int("int", -42),
+				slog.Uint64("uint", math.MaxUint64),
+				slog.Float64("float", 1.5),
+				slog.Bool("bool", true),
+				slog.Duration("duration", 1500*time.Millisecond),
+				slog.Time("at", recordTime),
+				slog.String("escaped\nkey", "\x00\x80"),
+			},
+			wantStream: true,
+		},
+		{
+			name: "any values",
+			attrs: []slog.Attr{
+				slog.Any("nil", nil),
+				slog.Any("struct", groupType{Bar: "baz"}),
+				slog.Any("cause", errors.New("not reported")),
+				slog.Any("stringer", testStringer{}),
+				slog.Any("addr", netip.MustParseAddr("192.0.2.1")),
+				slog.Any("raw", json.RawMessage(`{ "b": 1, "a": 2 }`)),
+				slog.Any("slice", []any{1, "two", nil}),
+				slog.Any("map", map[string]any{"b": 1, "a": "x"}),
+				Force(),
+			},
+			wantStream: true,
+		},
+		{
+			name: "groups",
+			attrs: []slog.Attr{
+				slog.Group("group", "b", 1, "a", slog.Group("nested", "c", true)),
+				slog.Any("valuer", groupTypeTest),
+				slog.Group("duplicate", "a", 1, "a", 2),
+			},
+			wantStream: true,
+		},
+		{
+			name: "WithAttrs",
+			with: func(l *slog.Logger) *slog.Logger {
+				return l.With("service", "svc", "count", 1).With("group", groupTypeTest, "map", map[string]any{"k": "v"})
+			},
+			attrs:      []slog.Attr{slog.String("key", "value")},
+			wantStream: true,
+		},
+		{
+			name: "options",
+			options: []Option{
+				WithHandlerOptions(&slog.HandlerOptions{AddSource: true}),
+				WithNumericSeverity(true),
+				WithUTC(true),
+				WithTimeKey("ts"),
+				WithSyslogPrefix(16),
+				WithNewline(false),
+			},
+			attrs:      []slog.Attr{slog.String("key", "value")},
+			wantStream: true,
+		},
+		{
+			name:       "lowercase severity and timestamp object",
+			options:    []Option{WithLowercaseSeverity(true), WithTimestamp(true)},
+			wantStream: true,
+		},
+		{
+			name:       "timestamp string",
+			options:    []Option{WithTimestamp(false), WithSourceMinLevel(slog.LevelInfo)},
+			wantStream: true,
+		},
+		{
+			name:       "trace context",
+			options:    []Option{WithProjectID("my-project")},
+			ctx:        traceCtx,
+			wantStream: true,
+		},
+		{
+			name:  "error",
+			attrs: []slog.Attr{slog.Any("error", errors.New("boom"))},
+		},
+		{
+			name:  "labels",
+			attrs: []slog.Attr{Labels("env", "prod")},
+		},
+		{
+			name:  "trace in group",
+			attrs: []slog.Attr{slog.Group("g", Trace("", "4bf92f3577b34da6a3ce929d0e0e4736"))},
+		},
+		{
+			name:  "duplicate keys",
+			attrs: []slog.Attr{slog.Int("key", 1), slog.Int("key", 2)},
+		},
+		{
+			name:  "duplicate WithAttrs key",
+			with:  func(l *slog.Logger) *slog.Logger { return l.With("key", 1) },
+			attrs: []slog.Attr{slog.Int("key", 2)},
+		},
+		{
+			name:  "reserved key",
+			attrs: []slog.Attr{slog.String(SeverityKey, "user")},
+		},
+		{
+			name:  "empty group",
+			attrs: []slog.Attr{slog.Any("g", emptyGroupValuer{})},
+		},
+		{
+			name:  "nested empty group",
+			attrs: []slog.Attr{slog.Group("g", slog.Any("h", emptyGroupValuer{}), "k", 1)},
+		},
+		{
+			name:  "WithGroup",
+			with:  func(l *slog.Logger) *slog.Logger { return l.WithGroup("g") },
+			attrs: []slog.Attr{slog.Int("key", 1)},
+		},
+		{
+			name:  "context attrs",
+			ctx:   ContextWithAttrs(context.Background(), slog.String("rid", "abc")),
+			attrs: []slog.Attr{slog.Int("key", 1)},
+		},
+		{
+			name:    "redaction",
+			options: []Option{WithRedactKeys("password")},
+			attrs:   []slog.Attr{slog.String("password", "hunter2")},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := tt.ctx
+			if ctx == nil {
+				ctx = context.Background()
+			}
+			var buf bytes.Buffer
+			logger := slog.New(NewHandler(&buf, tt.options...))
+			if tt.with != nil {
+				logger = tt.with(logger)
+			}
+			h := logger.Handler().(*handler)
+			r := slog.NewRecord(recordTime, slog.LevelInfo, "test message", pcs[0])
+			r.AddAttrs(tt.attrs...)
+
+			state := statePool.Get().(*handleState)
+			streamed := false
+			if h.stream {
+				var err error
+				if streamed, err = h.streamRecord(ctx, &r, state); err != nil {
+					t.Fatal(err)
+				}
+			}
+			state.free()
+			if streamed != tt.wantStream {
+				t.Errorf("streamed = %v, want %v", streamed, tt.wantStream)
+			}
+			if !streamed {
+				return
+			}
+			got := bytes.Clone(buf.Bytes())
+			buf.Reset()
+			slow := *h
+			slow.stream = false
+			if err := slow.Handle(ctx, r); err != nil {
+				t.Fatal(err)
+			}
+			if want := buf.Bytes(); !bytes.Equal(got, want) {
+				t.Errorf("streamed output =\n%s\nwant\n%s", got, want)
+			}
+		})
+	}
+}
+
+func TestHandler_streamRecord_encodeError(t *testing.T) {
+	var buf bytes.Buffer
+	h := NewHandler(&buf)
+	err := h.Handle(t.Context(), slog.NewRecord(time.Now(), slog.LevelInfo, "test message", 0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	buf.Reset()
+	r := slog.NewRecord(time.Now(), slog.LevelInfo, "test message", 0)
+	r.AddAttrs(slog.Float64("nan", math.NaN()))
+	if err := h.Handle(t.Context(), r); err == nil {
+		t.Error("Handle() = nil, want an error")
+	}
+	if buf.Len() != 0 {
+		t.Errorf("partial record written: %q", buf.Bytes())
+	}
+}
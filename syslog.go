@@ -0,0 +1,45 @@
+package sloggcp
+
+import "strconv"
+
+// userFacility is the syslog "user-level messages" facility.
+const userFacility = 1
+
+// WithSyslogPrefix prepends a syslog priority prefix, such as "<11>", to every JSON record,
+// for relays which expect the priority before the message, as in RFC 5424.
+// The priority is computed as facility * 8 + severity, where severity is the syslog severity
+// of the mapped GCP severity: 0 for [EmergencySeverity] up to 7 for [DebugSeverity].
+// [DefaultSeverity] and unknown severities map to the informational severity 6.
+// Facilities outside the range 0 to 23 are replaced by 1, the user-level facility.
+//
+// The JSON body is not modified. The option has no effect on the dev output of [WithDevMode].
+func WithSyslogPrefix(facility int) Option {
+	return func(c *config) {
+		if facility < 0 || facility > 23 {
+			facility = userFacility
+		}
+		c.syslogPrefix = true
+		c.syslogFacility = facility
+	}
+}
+
+// syslogSeverities maps GCP severities to syslog severities.
+var syslogSeverities = map[string]int{
+	EmergencySeverity: 0,
+	AlertSeverity:     1,
+	CriticalSeverity:  2,
+	ErrorSeverity:     3,
+	WarningSeverity:   4,
+	NoticeSeverity:    5,
+	InfoSeverity:      6,
+	DebugSeverity:     7,
+}
+
+// syslogPriority returns the "<PRI>" prefix for facility and the GCP severity.
+func syslogPriority(facility int, severity string) string {
+	s, ok := syslogSeverities[severity]
+	if !ok {
+		s = syslogSeverities[InfoSeverity]
+	}
+	return "<" + strconv.Itoa(facility*8+s) + ">"
+}
@@ -0,0 +1,72 @@
+package sloggcp
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"testing"
+)
+
+func TestWithSyslogPrefix(t *testing.T) {
+	tests := []struct {
+		name     string
+		options  []Option
+		level    slog.Level
+		wantPRI  string
+		severity any
+	}{
+		{
+			name:     "error, local0",
+			options:  []Option{WithSyslogPrefix(16)},
+			level:    slog.LevelError,
+			wantPRI:  "<131>",
+			severity: ErrorSeverity,
+		},
+		{
+			name:     "info, user",
+			options:  []Option{WithSyslogPrefix(1)},
+			level:    slog.LevelInfo,
+			wantPRI:  "<14>",
+			severity: InfoSeverity,
+		},
+		{
+			name:     "emergency, kernel",
+			options:  []Option{WithSyslogPrefix(0)},
+			level:    LevelEmergency,
+			wantPRI:  "<0>",
+			severity: EmergencySeverity,
+		},
+		{
+			name:     "invalid facility",
+			options:  []Option{WithSyslogPrefix(24)},
+			level:    slog.LevelWarn,
+			wantPRI:  "<12>",
+			severity: WarningSeverity,
+		},
+		{
+			name:     "numeric severity",
+			options:  []Option{WithSyslogPrefix(16), WithNumericSeverity(true)},
+			level:    slog.LevelError,
+			wantPRI:  "<131>",
+			severity: float64(500),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			slog.New(NewHandler(&buf, tt.options...)).Log(t.Context(), tt.level, "test message")
+
+			body, ok := bytes.CutPrefix(buf.Bytes(), []byte(tt.wantPRI))
+			if !ok {
+				t.Fatalf("output %q, want prefix %q", buf.String(), tt.wantPRI)
+			}
+			var got map[string]any
+			if err := json.Unmarshal(body, &got); err != nil {
+				t.Fatalf("Failed to decode log output: %v", err)
+			}
+			if got[SeverityKey] != tt.severity {
+				t.Errorf("severity = %v, want %v", got[SeverityKey], tt.severity)
+			}
+		})
+	}
+}
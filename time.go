@@ -0,0 +1,47 @@
+package sloggcp
+
+import "time"
+
+// timestampMode determines how the time attribute is rendered.
+type timestampMode int
+
+const (
+	timestampDefault timestampMode = iota // RFC3339 string under [TimeKey]
+	timestampString                       // RFC3339 string under [TimestampKey]
+	timestampObject                       // seconds and nanos object under [TimestampKey]
+)
+
+// timestamp is the JSON representation of a protobuf Timestamp.
+type timestamp struct {
+	Seconds int64 `json:"seconds"`
+	Nanos   int   `json:"nanos"`
+}
+
+func (c *config) timeKey() string {
+	if c.timeKeyName != "" {
+		return c.timeKeyName
+	}
+	if c.timestamp == timestampDefault {
+		return TimeKey
+	}
+	return TimestampKey
+}
+
+func (c *config) timeValue(t time.Time) any {
+	t = c.zone(t)
+	if c.timestamp == timestampObject {
+		return timestamp{
+			Seconds: t.Unix(),
+			Nanos:   t.Nanosecond(),
+		}
+	}
+	return t.Format(time.RFC3339Nano)
+}
+
+// zone returns t in UTC if [WithUTC] is enabled, otherwise t is returned unchanged.
+func (c *config) zone(t time.Time) time.Time {
+	if c.utc {
+		return t.UTC()
+	}
+	return t
+}
@@ -0,0 +1,226 @@
+package sloggcp
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWithTimestamp(t *testing.T) {
+	recordTime := time.Date(2025, 1, 2, 3, 4, 5, 123456789, time.UTC)
+
+	tests := []struct {
+		name    string
+		options []Option
+		wantKey string
+		decode  func(t *testing.T, raw json.RawMessage) time.Time
+	}{
+		{
+			name:    "default",
+			wantKey: TimeKey,
+			decode:  decodeTimeString,
+		},
+		{
+			name:    "timestamp string",
+			options: []Option{WithTimestamp(false)},
+			wantKey: TimestampKey,
+			decode:  decodeTimeString,
+		},
+		{
+			name:    "time key",
+			options: []Option{WithTimeKey("ts")},
+			wantKey: "ts",
+			decode:  decodeTimeString,
+		},
+		{
+			name:    "empty time key",
+			options: []Option{WithTimestamp(false), WithTimeKey("")},
+			wantKey: TimestampKey,
+			decode:  decodeTimeString,
+		},
+		{
+			name:    "timestamp object",
+			options: []Option{WithTimestamp(true)},
+			wantKey: TimestampKey,
+			decode: func(t *testing.T, raw json.RawMessage) time.Time {
+				var ts timestamp
+				if err := json.Unmarshal(raw, &ts); err != nil {
+					t.Fatal(err)
+				}
+				return time.Unix(ts.Seconds, int64(ts.Nanos)).UTC()
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handlers := map[string]func(*bytes.Buffer) slog.Handler{
+				"handler": func(buf *bytes.Buffer) slog.Handler {
+					return NewHandler(buf, tt.options...)
+				},
+				"ReplaceAttr": func(buf *bytes.Buffer) slog.Handler {
+					return slog.NewJSONHandler(buf, &slog.HandlerOptions{
+						ReplaceAttr: NewReplaceAttr(tt.options...),
+					})
+				},
+			}
+			for name, newHandler := range handlers {
+				var buf bytes.Buffer
+				r := slog.NewRecord(recordTime, slog.LevelInfo, "test message", 0)
+				if err := newHandler(&buf).Handle(t.Context(), r); err != nil {
+					t.Fatal(err)
+				}
+				var got map[string]json.RawMessage
+				if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+					t.Fatalf("%s: Failed to decode log output: %v", name, err)
+				}
+				raw, ok := got[tt.wantKey]
+				if !ok {
+					t.Fatalf("%s: missing key %q in %s", name, tt.wantKey, buf.String())
+				}
+				if tt.wantKey != TimeKey {
+					if _, ok := got[TimeKey]; ok {
+						t.Errorf("%s: unexpected key %q", name, TimeKey)
+					}
+				}
+				if gotTime := tt.decode(t, raw); !gotTime.Equal(recordTime) {
+					t.Errorf("%s: time = %v, want %v", name, gotTime, recordTime)
+				}
+			}
+		})
+	}
+}
+
+func decodeTimeString(t *testing.T, raw json.RawMessage) time.Time {
+	var s string
+	if err := json.Unmarshal(raw, &s); err != nil {
+		t.Fatal(err)
+	}
+	got, err := time.Parse(time.RFC3339Nano, s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return got
+}
+
+func TestWithTimeKey_special(t *testing.T) {
+	recordTime := time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)
+	r := slog.NewRecord(recordTime, slog.LevelInfo, "test message", 0)
+	r.AddAttrs(slog.String("foo", "bar"))
+
+	var buf bytes.Buffer
+	if err := NewHandler(&buf, WithTimeKey("ts"), WithPayloadGroup("payload")).Handle(t.Context(), r); err != nil {
+		t.Fatal(err)
+	}
+	var got map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+		t.Fatalf("Failed to decode log output: %v", err)
+	}
+	if got["ts"] != "2025-01-02T03:04:05Z" {
+		t.Errorf("ts = %v, want top-level time in %v", got["ts"], got)
+	}
+
+	e, err := RecordToEntry(t.Context(), r, nil, WithTimeKey("ts"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !e.Timestamp.Equal(recordTime) {
+		t.Errorf("Timestamp = %v, want %v", e.Timestamp, recordTime)
+	}
+	if want := `{"foo":"bar","message":"test message"}`; string(e.Payload) != want {
+		t.Errorf("Payload = %s, want %s", e.Payload, want)
+	}
+}
+
+func TestWithUTC(t *testing.T) {
+	zone := time.FixedZone("CEST", 2*60*60)
+	localTime := time.Date(2025, 6, 1, 14, 4, 5, 123456789, zone)
+	clock := func() time.Time { return localTime }
+	const want = "2025-06-01T12:04:05.123456789Z"
+
+	tests := []struct {
+		name    string
+		options []Option
+		time    time.Time
+		wantKey string
+		want    string
+	}{
+		{
+			name:    "time",
+			options: []Option{WithUTC(true)},
+			time:    localTime,
+			wantKey: TimeKey,
+			want:    want,
+		},
+		{
+			name:    "timestamp",
+			options: []Option{WithUTC(true), WithTimestamp(false)},
+			time:    localTime,
+			wantKey: TimestampKey,
+			want:    want,
+		},
+		{
+			name:    "event time",
+			options: []Option{WithUTC(true)},
+			time:    localTime,
+			wantKey: EventTimeKey,
+			want:    want,
+		},
+		{
+			name:    "event time from clock",
+			options: []Option{WithUTC(true), WithClock(clock)},
+			wantKey: EventTimeKey,
+			want:    want,
+		},
+		{
+			name:    "disabled",
+			time:    localTime,
+			wantKey: TimeKey,
+			want:    "2025-06-01T14:04:05.123456789+02:00",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			r := slog.NewRecord(tt.time, slog.LevelError, "test message", 0)
+			r.AddAttrs(slog.String("error", "boom"))
+			if err := NewHandler(&buf, tt.options...).Handle(t.Context(), r); err != nil {
+				t.Fatal(err)
+			}
+			var got map[string]any
+			if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+				t.Fatalf("Failed to decode log output: %v", err)
+			}
+			if got[tt.wantKey] != tt.want {
+				t.Errorf("%s = %v, want %v", tt.wantKey, got[tt.wantKey], tt.want)
+			}
+		})
+	}
+
+	t.Run("ReplaceAttr", func(t *testing.T) {
+		var buf bytes.Buffer
+		h := slog.NewJSONHandler(&buf, &slog.HandlerOptions{ReplaceAttr: NewReplaceAttr(WithUTC(true))})
+		if err := h.Handle(t.Context(), slog.NewRecord(localTime, slog.LevelInfo, "test message", 0)); err != nil {
+			t.Fatal(err)
+		}
+		var got map[string]any
+		if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+			t.Fatalf("Failed to decode log output: %v", err)
+		}
+		if got[TimeKey] != want {
+			t.Errorf("%s = %v, want %v", TimeKey, got[TimeKey], want)
+		}
+	})
+	t.Run("dev", func(t *testing.T) {
+		var buf bytes.Buffer
+		h := NewDevHandler(&buf, WithUTC(true))
+		if err := h.Handle(t.Context(), slog.NewRecord(localTime, slog.LevelInfo, "test message", 0)); err != nil {
+			t.Fatal(err)
+		}
+		if got := buf.String(); !strings.HasPrefix(got, "12:04:05.123 ") {
+			t.Errorf("dev output = %q, want UTC time 12:04:05.123", got)
+		}
+	})
+}
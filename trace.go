@@ -0,0 +1,199 @@
+package sloggcp
+
+import (
+	"context"
+	"encoding/binary"
+	"log/slog"
+	"strconv"
+	"strings"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Keys for trace related special fields in GCP structured logging.
+// See https://cloud.google.com/logging/docs/structured-logging#special-payload-fields.
+const (
+	TraceKey        = "logging.googleapis.com/trace"
+	SpanIDKey       = "logging.googleapis.com/spanId"
+	TraceSampledKey = "logging.googleapis.com/trace_sampled"
+)
+
+// CloudTraceHeader is the trace context header set by the Google Cloud load balancer.
+// See https://cloud.google.com/trace/docs/trace-context#legacy-http-header.
+const CloudTraceHeader = "X-Cloud-Trace-Context"
+
+// ParseCloudTraceHeader parses the value of a [CloudTraceHeader],
+// in the form of "TRACE_ID/SPAN_ID;o=OPTIONS".
+// TRACE_ID is a 32 character hexadecimal value and SPAN_ID is a decimal unsigned integer.
+// The ";o=OPTIONS" suffix is optional, sampled is true when OPTIONS is 1.
+// The returned ok is false if the header is malformed.
+func ParseCloudTraceHeader(header string) (traceID string, spanID uint64, sampled bool, ok bool) {
+	traceID, rest, found := strings.Cut(header, "/")
+	if !found {
+		return "", 0, false, false
+	}
+	traceID = strings.ToLower(traceID)
+	if _, err := trace.TraceIDFromHex(traceID); err != nil {
+		return "", 0, false, false
+	}
+	span, options, hasOptions := strings.Cut(rest, ";")
+	spanID, err := strconv.ParseUint(span, 10, 64)
+	if err != nil {
+		return "", 0, false, false
+	}
+	if hasOptions {
+		switch options {
+		case "o=1":
+			sampled = true
+		case "o=0":
+		default:
+			return "", 0, false, false
+		}
+	}
+	return traceID, spanID, sampled, true
+}
+
+// ContextWithTrace returns a copy of ctx carrying the trace values,
+// as returned by [ParseCloudTraceHeader].
+// The values are stored as a remote OpenTelemetry [trace.SpanContext],
+// so that the handler emits the trace fields for records logged with the returned context.
+// If traceID is not a valid hexadecimal trace ID or spanID is 0, ctx is returned unchanged.
+func ContextWithTrace(ctx context.Context, traceID string, spanID uint64, sampled bool) context.Context {
+	tid, err := trace.TraceIDFromHex(traceID)
+	if err != nil || spanID == 0 {
+		return ctx
+	}
+	var sid trace.SpanID
+	binary.BigEndian.PutUint64(sid[:], spanID)
+	var flags trace.TraceFlags
+	if sampled {
+		flags = trace.FlagsSampled
+	}
+	return trace.ContextWithRemoteSpanContext(ctx, trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID:    tid,
+		SpanID:     sid,
+		TraceFlags: flags,
+		Remote:     true,
+	}))
+}
+
+// traceName returns the fully qualified trace name for the given project.
+// If projectID is empty, the bare trace ID is returned.
+func traceName(projectID, traceID string) string {
+	if projectID == "" {
+		return traceID
+	}
+	return "projects/" + projectID + "/traces/" + traceID
+}
+
+// setTraceFields sets the trace special fields from the OpenTelemetry
+// [trace.SpanContext] found in ctx.
+// Nothing is set if ctx does not carry a valid span context.
+//
+// Fields set by attributes take precedence: if the trace is set, the span context is ignored,
+// so that the trace and span ID are not mixed from different sources.
+// Otherwise, only the fields which are not set yet are set from the span context.
+func setTraceFields(ctx context.Context, projectID string, out map[string]any) {
+	if _, ok := out[TraceKey]; ok {
+		return
+	}
+	sc := trace.SpanContextFromContext(ctx)
+	if !sc.IsValid() {
+		return
+	}
+	out[TraceKey] = traceName(projectID, sc.TraceID().String())
+	if _, ok := out[SpanIDKey]; !ok {
+		out[SpanIDKey] = sc.SpanID().String()
+	}
+	if _, ok := out[TraceSampledKey]; !ok {
+		out[TraceSampledKey] = sc.IsSampled()
+	}
+}
+
+// checkAndSetTrace sets the [TraceKey] field in out, at any group depth.
+// Empty values are dropped.
+// It returns false if a is not a trace attribute.
+func checkAndSetTrace(a slog.Attr, out map[string]any) bool {
+	if a.Key != TraceKey {
+		return false
+	}
+	if s := a.Value.Resolve().String(); s != "" {
+		out[TraceKey] = s
+	}
+	return true
+}
+
+// TraceSampled returns an attribute for the [TraceSampledKey] special field,
+// for records which set the trace fields manually.
+// The value is a JSON boolean. Without the attribute, the field is omitted,
+// which Cloud Logging treats differently from false.
+func TraceSampled(sampled bool) slog.Attr {
+	return slog.Bool(TraceSampledKey, sampled)
+}
+
+// Trace returns an attribute for the [TraceKey] special field,
+// for records which set the trace fields manually.
+// The value is the fully qualified trace name "projects/PROJECT_ID/traces/TRACE_ID".
+// The attribute takes precedence over the span context of the [context.Context] passed to the handler,
+// and it is emitted at the top level, even when logged inside a group.
+// The traceID is normalized to lower case.
+//
+// Invalid values are logged on a best-effort basis:
+// if projectID is empty, the bare trace ID is used,
+// and a traceID which is not a 32 character hexadecimal value is used as is.
+// If traceID is empty, the zero [slog.Attr] is returned, which is dropped by the handler.
+func Trace(projectID, traceID string) slog.Attr {
+	if traceID == "" {
+		return slog.Attr{}
+	}
+	if lower := strings.ToLower(traceID); validTraceID(lower) {
+		traceID = lower
+	}
+	return slog.String(TraceKey, traceName(projectID, traceID))
+}
+
+// validTraceID reports whether id is a 32 character, lower case hexadecimal, non-zero trace ID.
+func validTraceID(id string) bool {
+	_, err := trace.TraceIDFromHex(id)
+	return err == nil
+}
+
+// SpanID returns an attribute for the [SpanIDKey] special field,
+// for records which set the trace fields manually.
+// GCP expects the span ID as a 16 character hexadecimal string.
+// The id is normalized to lower case and shorter IDs are left-padded with zeros.
+// If id is not a hexadecimal number of at most 16 digits,
+// the zero [slog.Attr] is returned, which is dropped by the handler.
+//
+// The handler always emits the field at the top level, even when logged inside a group.
+func SpanID(id string) slog.Attr {
+	id, ok := normalizeSpanID(id)
+	if !ok {
+		return slog.Attr{}
+	}
+	return slog.String(SpanIDKey, id)
+}
+
+// normalizeSpanID returns id as 16 digit lower case hexadecimal string.
+func normalizeSpanID(id string) (string, bool) {
+	if id == "" || len(id) > 16 {
+		return "", false
+	}
+	if _, err := strconv.ParseUint(id, 16, 64); err != nil {
+		return "", false
+	}
+	return leftPad(strings.ToLower(id), 16), true
+}
+
+// checkAndSetSpanID sets the [SpanIDKey] field in out, at any group depth.
+// String values are normalized the same as by [SpanID], invalid values are dropped.
+// It returns false if a is not a span ID attribute.
+func checkAndSetSpanID(a slog.Attr, out map[string]any) bool {
+	if a.Key != SpanIDKey {
+		return false
+	}
+	if id, ok := normalizeSpanID(a.Value.Resolve().String()); ok {
+		out[SpanIDKey] = id
+	}
+	return true
+}
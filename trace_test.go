@@ -0,0 +1,463 @@
+package sloggcp
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"reflect"
+	"testing"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+var (
+	testTraceID = trace.TraceID{0x4b, 0xf9, 0x2f, 0x35, 0x77, 0xb3, 0x4d, 0xa6, 0xa3, 0xce, 0x92, 0x9d, 0x0e, 0x0e, 0x47, 0x36}
+	testSpanID  = trace.SpanID{0x00, 0xf0, 0x67, 0xaa, 0x0b, 0xa9, 0x02, 0xb7}
+)
+
+func Test_traceName(t *testing.T) {
+	tests := []struct {
+		name      string
+		projectID string
+		traceID   string
+		want      string
+	}{
+		{
+			name:      "with project",
+			projectID: "my-project",
+			traceID:   "abc",
+			want:      "projects/my-project/traces/abc",
+		},
+		{
+			name:    "without project",
+			traceID: "abc",
+			want:    "abc",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := traceName(tt.projectID, tt.traceID); got != tt.want {
+				t.Errorf("traceName() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHandler_trace(t *testing.T) {
+	tests := []struct {
+		name string
+		ctx  context.Context
+		want map[string]any
+	}{
+		{
+			name: "no span context",
+			ctx:  context.Background(),
+			want: map[string]any{},
+		},
+		{
+			name: "sampled",
+			ctx: trace.ContextWithSpanContext(context.Background(), trace.NewSpanContext(trace.SpanContextConfig{
+				TraceID:    testTraceID,
+				SpanID:     testSpanID,
+				TraceFlags: trace.FlagsSampled,
+			})),
+			want: map[string]any{
+				TraceKey:        "projects/my-project/traces/4bf92f3577b34da6a3ce929d0e0e4736",
+				SpanIDKey:       "00f067aa0ba902b7",
+				TraceSampledKey: true,
+			},
+		},
+		{
+			name: "not sampled",
+			ctx: trace.ContextWithSpanContext(context.Background(), trace.NewSpanContext(trace.SpanContextConfig{
+				TraceID: testTraceID,
+				SpanID:  testSpanID,
+			})),
+			want: map[string]any{
+				TraceKey:        "projects/my-project/traces/4bf92f3577b34da6a3ce929d0e0e4736",
+				SpanIDKey:       "00f067aa0ba902b7",
+				TraceSampledKey: false,
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			logger := slog.New(NewErrorReportingHandler(&buf, nil, WithProjectID("my-project")))
+			logger.InfoContext(tt.ctx, "test message")
+
+			var out map[string]any
+			if err := json.Unmarshal(buf.Bytes(), &out); err != nil {
+				t.Fatalf("Failed to decode log output: %v", err)
+			}
+			got := make(map[string]any)
+			for _, k := range []string{TraceKey, SpanIDKey, TraceSampledKey} {
+				if v, ok := out[k]; ok {
+					got[k] = v
+				}
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("trace fields = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseCloudTraceHeader(t *testing.T) {
+	tests := []struct {
+		name        string
+		header      string
+		wantTraceID string
+		wantSpanID  uint64
+		wantSampled bool
+		wantOK      bool
+	}{
+		{
+			name:        "sampled",
+			header:      "4bf92f3577b34da6a3ce929d0e0e4736/67667974448284343;o=1",
+			wantTraceID: "4bf92f3577b34da6a3ce929d0e0e4736",
+			wantSpanID:  67667974448284343,
+			wantSampled: true,
+			wantOK:      true,
+		},
+		{
+			name:        "not sampled",
+			header:      "4bf92f3577b34da6a3ce929d0e0e4736/67667974448284343;o=0",
+			wantTraceID: "4bf92f3577b34da6a3ce929d0e0e4736",
+			wantSpanID:  67667974448284343,
+			wantOK:      true,
+		},
+		{
+			name:        "without options",
+			header:      "4BF92F3577B34DA6A3CE929D0E0E4736/1",
+			wantTraceID: "4bf92f3577b34da6a3ce929d0e0e4736",
+			wantSpanID:  1,
+			wantOK:      true,
+		},
+		{
+			name:   "empty",
+			header: "",
+		},
+		{
+			name:   "missing span",
+			header: "4bf92f3577b34da6a3ce929d0e0e4736",
+		},
+		{
+			name:   "short trace",
+			header: "4bf92f35/1;o=1",
+		},
+		{
+			name:   "invalid trace",
+			header: "zzf92f3577b34da6a3ce929d0e0e4736/1;o=1",
+		},
+		{
+			name:   "hex span",
+			header: "4bf92f3577b34da6a3ce929d0e0e4736/00f067aa0ba902b7;o=1",
+		},
+		{
+			name:   "invalid options",
+			header: "4bf92f3577b34da6a3ce929d0e0e4736/1;o=2",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotTraceID, gotSpanID, gotSampled, gotOK := ParseCloudTraceHeader(tt.header)
+			if gotTraceID != tt.wantTraceID {
+				t.Errorf("ParseCloudTraceHeader() traceID = %v, want %v", gotTraceID, tt.wantTraceID)
+			}
+			if gotSpanID != tt.wantSpanID {
+				t.Errorf("ParseCloudTraceHeader() spanID = %v, want %v", gotSpanID, tt.wantSpanID)
+			}
+			if gotSampled != tt.wantSampled {
+				t.Errorf("ParseCloudTraceHeader() sampled = %v, want %v", gotSampled, tt.wantSampled)
+			}
+			if gotOK != tt.wantOK {
+				t.Errorf("ParseCloudTraceHeader() ok = %v, want %v", gotOK, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestContextWithTrace(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(NewErrorReportingHandler(&buf, nil, WithProjectID("my-project")))
+
+	traceID, spanID, sampled, ok := ParseCloudTraceHeader("4bf92f3577b34da6a3ce929d0e0e4736/67667974448284343;o=1")
+	if !ok {
+		t.Fatal("ParseCloudTraceHeader() not ok")
+	}
+	ctx := ContextWithTrace(context.Background(), traceID, spanID, sampled)
+	logger.InfoContext(ctx, "test message")
+
+	var got map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+		t.Fatalf("Failed to decode log output: %v", err)
+	}
+	want := map[string]any{
+		TraceKey:        "projects/my-project/traces/4bf92f3577b34da6a3ce929d0e0e4736",
+		SpanIDKey:       "00f067aa0ba902b7",
+		TraceSampledKey: true,
+	}
+	for k, v := range want {
+		if got[k] != v {
+			t.Errorf("%s = %v, want %v", k, got[k], v)
+		}
+	}
+}
+
+func TestContextWithTrace_invalid(t *testing.T) {
+	ctx := context.Background()
+	if got := ContextWithTrace(ctx, "invalid", 1, true); got != ctx {
+		t.Errorf("ContextWithTrace() returned a new context for an invalid trace ID")
+	}
+	if got := ContextWithTrace(ctx, "4bf92f3577b34da6a3ce929d0e0e4736", 0, true); got != ctx {
+		t.Errorf("ContextWithTrace() returned a new context for a zero span ID")
+	}
+}
+
+func TestTraceSampled(t *testing.T) {
+	tests := []struct {
+		name  string
+		attrs []any
+		want  any
+		isSet bool
+	}{
+		{
+			name:  "unset",
+			attrs: []any{slog.String(TraceKey, "projects/p/traces/t")},
+		},
+		{
+			name:  "true",
+			attrs: []any{TraceSampled(true)},
+			want:  true,
+			isSet: true,
+		},
+		{
+			name:  "false",
+			attrs: []any{TraceSampled(false)},
+			want:  false,
+			isSet: true,
+		},
+	}
+	var handlerBuf, replaceBuf bytes.Buffer
+	handlers := map[string]struct {
+		h   slog.Handler
+		buf *bytes.Buffer
+	}{
+		"handler":     {NewHandler(&handlerBuf), &handlerBuf},
+		"ReplaceAttr": {slog.NewJSONHandler(&replaceBuf, &slog.HandlerOptions{ReplaceAttr: ReplaceAttr}), &replaceBuf},
+	}
+	for name, h := range handlers {
+		for _, tt := range tests {
+			t.Run(name+"/"+tt.name, func(t *testing.T) {
+				h.buf.Reset()
+				slog.New(h.h).Info("test message", tt.attrs...)
+
+				var got map[string]any
+				if err := json.Unmarshal(h.buf.Bytes(), &got); err != nil {
+					t.Fatalf("Failed to decode log output: %v", err)
+				}
+				value, ok := got[TraceSampledKey]
+				if ok != tt.isSet {
+					t.Fatalf("%s set = %v, want %v", TraceSampledKey, ok, tt.isSet)
+				}
+				if value != tt.want {
+					t.Errorf("%s = %#v, want %#v", TraceSampledKey, value, tt.want)
+				}
+			})
+		}
+	}
+}
+
+func TestSpanID(t *testing.T) {
+	tests := []struct {
+		name  string
+		attr  slog.Attr
+		want  any
+		isSet bool
+	}{
+		{
+			name:  "valid",
+			attr:  SpanID("00f067aa0ba902b7"),
+			want:  "00f067aa0ba902b7",
+			isSet: true,
+		},
+		{
+			name:  "padded and lower case",
+			attr:  SpanID("F067AA0BA902B7"),
+			want:  "00f067aa0ba902b7",
+			isSet: true,
+		},
+		{
+			name:  "inside group",
+			attr:  slog.Group("group", SpanID("1")),
+			want:  "0000000000000001",
+			isSet: true,
+		},
+		{
+			name:  "raw attribute",
+			attr:  slog.String(SpanIDKey, "ABC"),
+			want:  "0000000000000abc",
+			isSet: true,
+		},
+		{
+			name: "too long",
+			attr: SpanID("00f067aa0ba902b7a"),
+		},
+		{
+			name: "not hex",
+			attr: SpanID("xyz"),
+		},
+		{
+			name: "invalid raw attribute",
+			attr: slog.String(SpanIDKey, "xyz"),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			slog.New(NewHandler(&buf)).Info("test message", tt.attr)
+
+			var got map[string]any
+			if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+				t.Fatalf("Failed to decode log output: %v", err)
+			}
+			value, ok := got[SpanIDKey]
+			if ok != tt.isSet || value != tt.want {
+				t.Errorf("%s = %v (set %v), want %v (set %v)", SpanIDKey, value, ok, tt.want, tt.isSet)
+			}
+			if _, ok := got[TraceKey]; ok {
+				t.Errorf("%s set without trace", TraceKey)
+			}
+		})
+	}
+}
+
+func TestTrace(t *testing.T) {
+	tests := []struct {
+		name      string
+		projectID string
+		traceID   string
+		want      slog.Attr
+	}{
+		{
+			name:      "valid",
+			projectID: "my-project",
+			traceID:   "4bf92f3577b34da6a3ce929d0e0e4736",
+			want:      slog.String(TraceKey, "projects/my-project/traces/4bf92f3577b34da6a3ce929d0e0e4736"),
+		},
+		{
+			name:      "upper case",
+			projectID: "my-project",
+			traceID:   "4BF92F3577B34DA6A3CE929D0E0E4736",
+			want:      slog.String(TraceKey, "projects/my-project/traces/4bf92f3577b34da6a3ce929d0e0e4736"),
+		},
+		{
+			name:    "empty project",
+			traceID: "4bf92f3577b34da6a3ce929d0e0e4736",
+			want:    slog.String(TraceKey, "4bf92f3577b34da6a3ce929d0e0e4736"),
+		},
+		{
+			name:      "too short",
+			projectID: "my-project",
+			traceID:   "4BF92F",
+			want:      slog.String(TraceKey, "projects/my-project/traces/4BF92F"),
+		},
+		{
+			name:      "not hex",
+			projectID: "my-project",
+			traceID:   "xyz92f3577b34da6a3ce929d0e0e4736",
+			want:      slog.String(TraceKey, "projects/my-project/traces/xyz92f3577b34da6a3ce929d0e0e4736"),
+		},
+		{
+			name:      "empty trace ID",
+			projectID: "my-project",
+			want:      slog.Attr{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Trace(tt.projectID, tt.traceID); !got.Equal(tt.want) {
+				t.Errorf("Trace() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHandler_tracePrecedence(t *testing.T) {
+	ctx := trace.ContextWithSpanContext(context.Background(), trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID:    testTraceID,
+		SpanID:     testSpanID,
+		TraceFlags: trace.FlagsSampled,
+	}))
+	const explicitTrace = "projects/my-project/traces/0123456789abcdef0123456789abcdef"
+	tests := []struct {
+		name  string
+		ctx   context.Context
+		attrs []any
+		want  map[string]any
+	}{
+		{
+			name: "context only",
+			ctx:  ctx,
+			want: map[string]any{
+				TraceKey:        "projects/my-project/traces/" + testTraceID.String(),
+				SpanIDKey:       testSpanID.String(),
+				TraceSampledKey: true,
+			},
+		},
+		{
+			name:  "attr only",
+			ctx:   context.Background(),
+			attrs: []any{Trace("my-project", "0123456789ABCDEF0123456789ABCDEF")},
+			want: map[string]any{
+				TraceKey: explicitTrace,
+			},
+		},
+		{
+			name:  "both",
+			ctx:   ctx,
+			attrs: []any{Trace("my-project", "0123456789abcdef0123456789abcdef"), SpanID("1")},
+			want: map[string]any{
+				TraceKey:  explicitTrace,
+				SpanIDKey: "0000000000000001",
+			},
+		},
+		{
+			name:  "both, attr in group",
+			ctx:   ctx,
+			attrs: []any{slog.Group("upstream", Trace("my-project", "0123456789abcdef0123456789abcdef"))},
+			want: map[string]any{
+				TraceKey: explicitTrace,
+			},
+		},
+		{
+			name:  "context with explicit span ID",
+			ctx:   ctx,
+			attrs: []any{SpanID("1"), TraceSampled(false)},
+			want: map[string]any{
+				TraceKey:        "projects/my-project/traces/" + testTraceID.String(),
+				SpanIDKey:       "0000000000000001",
+				TraceSampledKey: false,
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			slog.New(NewHandler(&buf, WithProjectID("my-project"))).InfoContext(tt.ctx, "test message", tt.attrs...)
+
+			var got map[string]any
+			if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+				t.Fatalf("Failed to decode log output: %v", err)
+			}
+			for _, k := range []string{TimeKey, SeverityKey, MessageKey} {
+				delete(got, k)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("log output = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}